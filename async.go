@@ -0,0 +1,19 @@
+package retrier
+
+import "context"
+
+// RunAsyncCtx executes a work task the same way as RunCtx in a new goroutine,
+// and returns a channel that receives the error of the run once it finishes.
+// The channel is buffered, so the goroutine does not leak if the error is
+// never received, and it is closed after the error is sent.
+func (r *Retrier) RunAsyncCtx(
+	ctx context.Context,
+	work func(ctx context.Context) (error, bool),
+) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		defer close(ch)
+		ch <- r.RunCtx(ctx, work)
+	}()
+	return ch
+}
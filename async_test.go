@@ -0,0 +1,63 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRunAsyncCtx tests if the error of a run executed in the background is
+// received from the channel, and the channel is closed afterwards
+func TestRunAsyncCtx(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Error error
+		Retry bool
+		Want  error
+	}{
+		{
+			Name:  "Success",
+			Error: nil,
+			Retry: false,
+			Want:  nil,
+		},
+		{
+			Name:  "Failure after max retries",
+			Error: fmt.Errorf("error"),
+			Retry: true,
+			Want:  fmt.Errorf("failed after max retries: error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, ConstantDelay(time.Millisecond))
+
+			ch := retr.RunAsyncCtx(
+				context.TODO(),
+				func(ctx context.Context) (error, bool) {
+					return test.Error, test.Retry
+				},
+			)
+
+			var err error
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case err = <-ch:
+			}
+
+			if test.Want != nil {
+				assert.EqualError(t, err, test.Want.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			_, open := <-ch
+			assert.False(t, open)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package retrier
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Attempt describes an attempt of a task while it is executing.
+type Attempt struct {
+	// Number is the number of the attempt, starting from 1.
+	Number int
+
+	// StartTime is the time when the attempt started.
+	StartTime time.Time
+
+	// LastErr is the error of the previous attempt, or nil on the first
+	// attempt.
+	LastErr error
+
+	// NextDelay is the delay before the next attempt if this attempt fails, as
+	// scheduled by the delay function. Errors requesting a specific delay and
+	// cooldowns may override it. It is zero on the last attempt.
+	NextDelay time.Duration
+
+	// TotalElapsed is the time elapsed since the start of the run when the
+	// attempt started.
+	TotalElapsed time.Duration
+
+	// Delay is the delay that was slept before the attempt, or zero on the
+	// first attempt.
+	Delay time.Duration
+}
+
+// attemptKey is the context key of the attempt that is executing.
+type attemptKey struct{}
+
+// nameKey is the context key of the name of the retrier.
+type nameKey struct{}
+
+// metadataKey is the context key of the metadata of the attempt.
+type metadataKey struct{}
+
+// AttemptFromContext returns the description of the attempt that is executing
+// from the context passed to a task, so that libraries called by the task can
+// annotate their output with it. It reports false outside of an attempt.
+func AttemptFromContext(ctx context.Context) (Attempt, bool) {
+	att, ok := ctx.Value(attemptKey{}).(Attempt)
+	return att, ok
+}
+
+// NameFromContext returns the name of the retrier from the context passed to
+// a task. It reports false outside of an attempt, or if the retrier has no
+// name.
+func NameFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(nameKey{}).(string)
+	return name, ok
+}
+
+// MetadataFromContext returns the metadata of the attempt that is executing
+// from the context passed to a task or an interceptor, as returned by the
+// metadata function of WithAttemptMetadata. It reports false outside of an
+// attempt, or if the attempt has no metadata.
+func MetadataFromContext(ctx context.Context) (map[string]any, bool) {
+	meta, ok := ctx.Value(metadataKey{}).(map[string]any)
+	return meta, ok
+}
+
+// RunCtxAttempt executes a work task the same way as RunCtx, and passes the
+// description of the current attempt to the task, so that it can adjust its
+// behavior on later attempts.
+func (r *Retrier) RunCtxAttempt(
+	ctx context.Context,
+	work func(ctx context.Context, att Attempt) (error, bool),
+) error {
+	_, err := r.run(ctx, OpAny, func(ctx context.Context) (error, bool) {
+		att, _ := AttemptFromContext(ctx)
+		return work(ctx, att)
+	})
+	return err
+}
+
+// DetachAttempt derives a context for work that starts in an attempt but
+// outlives it, such as reading the body of a response or receiving from a
+// stream, which would fail if the context of the attempt was canceled when the
+// attempt ends. The context has the values of the attempt context, such as
+// the description of the attempt and the span that traces it, and is canceled
+// with the attempt context until detach is called. After that, it is only
+// canceled with the parent context or with cancel, which must be called to
+// release it when the work is done.
+func DetachAttempt(
+	parent context.Context,
+	actx context.Context,
+) (ctx context.Context, detach func(), cancel context.CancelFunc) {
+	ctx, cancel = context.WithCancel(valuesContext{parent, actx})
+	stop := make(chan struct{})
+	once := sync.Once{}
+	go func() {
+		select {
+		case <-actx.Done():
+			// The attempt may end after being detached, in which case both
+			// channels are closed and either case could be selected.
+			select {
+			case <-stop:
+			default:
+				cancel()
+			}
+		case <-stop:
+		case <-ctx.Done():
+		}
+	}()
+
+	detach = func() {
+		once.Do(func() { close(stop) })
+	}
+	return ctx, detach, cancel
+}
+
+// valuesContext is a context that takes its values from another context.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+// Value returns the value of a key from the context of the values.
+func (c valuesContext) Value(key any) any {
+	return c.values.Value(key)
+}
@@ -0,0 +1,190 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRunCtxAttempt tests if the task receives the description of every
+// attempt, including the error of the previous attempt and the next delay
+func TestRunCtxAttempt(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Max    int
+		Delays []time.Duration
+	}{
+		{
+			Name: "Limited retries",
+			Max:  2,
+			Delays: []time.Duration{
+				time.Millisecond * 5,
+				time.Millisecond * 10,
+				0,
+			},
+		},
+		{
+			Name: "No retries",
+			Max:  0,
+			Delays: []time.Duration{
+				0,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(test.Max, LinearDelay(time.Millisecond*5))
+
+			atts := []Attempt{}
+			st := time.Now()
+			retr.RunCtxAttempt(
+				context.TODO(),
+				func(ctx context.Context, att Attempt) (error, bool) {
+					atts = append(atts, att)
+					return fmt.Errorf("error %d", att.Number), true
+				},
+			)
+
+			if assert.Len(t, atts, len(test.Delays)) {
+				for i, att := range atts {
+					assert.Equal(t, i+1, att.Number)
+					assert.Equal(t, test.Delays[i], att.NextDelay)
+					assert.InDelta(
+						t,
+						float64(att.StartTime.Sub(st)),
+						float64(att.TotalElapsed),
+						float64(time.Millisecond),
+					)
+					if i == 0 {
+						assert.NoError(t, att.LastErr)
+					} else {
+						assert.EqualError(t, att.LastErr, fmt.Sprintf("error %d", i))
+						assert.GreaterOrEqual(
+							t,
+							att.TotalElapsed,
+							atts[i-1].TotalElapsed+test.Delays[i-1],
+						)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestAttemptFromContext tests if the attempt number and the name of the
+// retrier can be read from the context passed to the task
+func TestAttemptFromContext(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Options []Option
+		Retrier string
+		Named   bool
+	}{
+		{
+			Name:    "Named retrier",
+			Options: []Option{WithName("payments")},
+			Retrier: "payments",
+			Named:   true,
+		},
+		{
+			Name:    "Unnamed retrier",
+			Options: []Option{},
+			Retrier: "",
+			Named:   false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, NoDelay(), test.Options...)
+
+			numbers := []int{}
+			retr.RunCtx(context.TODO(), func(ctx context.Context) (error, bool) {
+				att, ok := AttemptFromContext(ctx)
+				assert.True(t, ok)
+				numbers = append(numbers, att.Number)
+
+				name, ok := NameFromContext(ctx)
+				assert.Equal(t, test.Named, ok)
+				assert.Equal(t, test.Retrier, name)
+
+				_, ok = MetadataFromContext(ctx)
+				assert.False(t, ok)
+				return fmt.Errorf("error"), true
+			})
+
+			assert.Equal(t, []int{1, 2, 3}, numbers)
+		})
+	}
+
+	_, ok := AttemptFromContext(context.TODO())
+	assert.False(t, ok)
+}
+
+// TestDetachAttempt tests if a detached context has the values of the attempt,
+// is canceled with the attempt until it is detached, and is canceled with the
+// parent context after that
+func TestDetachAttempt(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Detach   bool
+		Canceled bool
+	}{
+		{
+			Name:     "Canceled with the attempt",
+			Detach:   false,
+			Canceled: true,
+		},
+		{
+			Name:     "Detached from the attempt",
+			Detach:   true,
+			Canceled: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			parent, cancelParent := context.WithCancel(context.Background())
+			defer cancelParent()
+
+			var ctx context.Context
+			var cancel context.CancelFunc
+			retr := NewRetrier(0, NoDelay(),
+				WithName("users"),
+				WithAttemptTimeout(time.Second),
+			)
+			retr.RunCtx(parent, func(actx context.Context) (error, bool) {
+				var detach func()
+				ctx, detach, cancel = DetachAttempt(parent, actx)
+				if test.Detach {
+					detach()
+				}
+				return nil, false
+			})
+			defer cancel()
+
+			att, ok := AttemptFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, 1, att.Number)
+			name, _ := NameFromContext(ctx)
+			assert.Equal(t, "users", name)
+
+			if test.Canceled {
+				assert.Eventually(t, func() bool {
+					return ctx.Err() != nil
+				}, time.Second, time.Millisecond)
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+			assert.NoError(t, ctx.Err())
+			cancelParent()
+			assert.Eventually(t, func() bool {
+				return ctx.Err() == context.Canceled
+			}, time.Second, time.Millisecond)
+		})
+	}
+}
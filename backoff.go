@@ -0,0 +1,35 @@
+package retrier
+
+import "time"
+
+// Backoff is a stateful source of delays between retries, for strategies that
+// need to carry state from one delay to the next, such as adaptive backoff.
+// A retrier created with a backoff resets it at the start of every run, and
+// asks it for the next delay before every retry. Concurrent runs share the
+// same backoff, so it should be safe for concurrent use, or the retrier should
+// not run tasks concurrently.
+type Backoff interface {
+	// Next returns the duration to wait before the next retry.
+	Next() time.Duration
+
+	// Reset restores the initial state of the backoff.
+	Reset()
+}
+
+// NewBackoffRetrier creates a retrier from max retries, a stateful backoff
+// and optional configuration.
+func NewBackoffRetrier(
+	max int,
+	backoff Backoff,
+	opts ...Option,
+) *Retrier {
+	r := NewRetrier(
+		max,
+		func(int) time.Duration {
+			return backoff.Next()
+		},
+		opts...,
+	)
+	r.backoff = backoff
+	return r
+}
@@ -0,0 +1,67 @@
+package retrier
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// doublingBackoff is a backoff that doubles its delay for every retry.
+type doublingBackoff struct {
+	delay  time.Duration
+	resets int
+}
+
+func (b *doublingBackoff) Next() time.Duration {
+	b.delay *= 2
+	return b.delay
+}
+
+func (b *doublingBackoff) Reset() {
+	b.delay = time.Millisecond / 2
+	b.resets++
+}
+
+// TestNewBackoffRetrier tests if a retrier created with a backoff takes its
+// delays from the backoff, and resets the backoff at the start of every run
+func TestNewBackoffRetrier(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Runs    int
+		Elapsed time.Duration
+	}{
+		{
+			Name:    "Single run",
+			Runs:    1,
+			Elapsed: time.Millisecond * 7,
+		},
+		{
+			Name:    "Reused retrier",
+			Runs:    3,
+			Elapsed: time.Millisecond * 7,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			bo := &doublingBackoff{}
+			retr := NewBackoffRetrier(3, bo)
+
+			for i := 0; i < test.Runs; i++ {
+				st := time.Now()
+				err := retr.Run(func() (error, bool) {
+					return fmt.Errorf("error"), true
+				})
+				dif := time.Since(st)
+
+				assert.EqualError(t, err, "failed after max retries: error")
+				assert.GreaterOrEqual(t, dif, test.Elapsed)
+				assert.Equal(t, time.Millisecond*4, bo.delay)
+			}
+
+			assert.Equal(t, test.Runs, bo.resets)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package retrier
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Budget is a token bucket of retries that can be shared by many retriers to
+// limit the rate of retries across a whole process. Every retry takes a token
+// from the bucket, and the bucket refills at a steady rate up to its capacity.
+// When the bucket is empty, retriers stop retrying instead of waiting, which
+// protects downstream services from retry storms. A budget is safe for
+// concurrent use.
+type Budget struct {
+	mu     sync.Mutex
+	cap    float64
+	tokens float64
+	rate   float64
+	last   time.Time
+}
+
+// NewBudget creates a budget that allows some number of retries per window of
+// time. The bucket starts full, so bursts of up to that many retries are
+// allowed at any time. A negative number of retries is replaced with zero, and
+// if the window is not positive, the bucket never refills, so the budget
+// allows that many retries in total.
+func NewBudget(retries int, window time.Duration) *Budget {
+	if retries < 0 {
+		retries = 0
+	}
+	rate := 0.0
+	if window > 0 {
+		rate = float64(retries) / float64(window)
+	}
+
+	return &Budget{
+		cap:    float64(retries),
+		tokens: float64(retries),
+		rate:   rate,
+		last:   time.Now(),
+	}
+}
+
+// NewBudgetE creates a budget the same way as NewBudget, but validates the
+// configuration first, and returns an error wrapping ErrInvalidConfig if the
+// number of retries is negative or the window is not positive.
+func NewBudgetE(retries int, window time.Duration) (*Budget, error) {
+	if retries < 0 {
+		return nil, fmt.Errorf(
+			"%w: budget retries %d is negative",
+			ErrInvalidConfig, retries,
+		)
+	} else if window <= 0 {
+		return nil, fmt.Errorf(
+			"%w: budget window %v is not positive",
+			ErrInvalidConfig, window,
+		)
+	}
+	return NewBudget(retries, window), nil
+}
+
+// Allow takes a token from the budget, and reports whether there was one left.
+func (b *Budget) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += float64(now.Sub(b.last)) * b.rate
+	if b.tokens > b.cap {
+		b.tokens = b.cap
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
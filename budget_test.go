@@ -0,0 +1,147 @@
+package retrier
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBudget tests if retriers sharing a budget stop retrying when the budget
+// runs out, and retry again after it refills
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Pause    time.Duration
+		Attempts []int
+	}{
+		{
+			Name:     "Budget runs out",
+			Pause:    0,
+			Attempts: []int{4, 2, 1},
+		},
+		{
+			Name:     "Budget refills",
+			Pause:    time.Millisecond * 60,
+			Attempts: []int{4, 4, 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			bgt := NewBudget(4, time.Millisecond*100)
+			retrs := []*Retrier{
+				NewRetrier(3, NoDelay(), WithBudget(bgt)),
+				NewRetrier(3, NoDelay(), WithBudget(bgt)),
+				NewRetrier(3, NoDelay(), WithBudget(bgt)),
+			}
+
+			attempts := []int{}
+			for _, retr := range retrs {
+				res, err := retr.RunResult(func() (error, bool) {
+					return fmt.Errorf("error"), true
+				})
+				assert.Error(t, err)
+				attempts = append(attempts, res.Attempts)
+				time.Sleep(test.Pause)
+			}
+
+			assert.Equal(t, test.Attempts, attempts)
+		})
+	}
+}
+
+// TestNewBudgetE tests if creating a budget with an invalid configuration is
+// rejected with ErrInvalidConfig
+func TestNewBudgetE(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Retries int
+		Window  time.Duration
+		Error   string
+	}{
+		{
+			Name:    "Valid budget",
+			Retries: 4,
+			Window:  time.Second,
+		},
+		{
+			Name:    "Negative retries",
+			Retries: -1,
+			Window:  time.Second,
+			Error:   "invalid retrier configuration: budget retries -1 is negative",
+		},
+		{
+			Name:    "Zero window",
+			Retries: 4,
+			Window:  0,
+			Error:   "invalid retrier configuration: budget window 0s is not positive",
+		},
+		{
+			Name:    "Negative window",
+			Retries: 4,
+			Window:  -time.Second,
+			Error:   "invalid retrier configuration: budget window -1s is not positive",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			bgt, err := NewBudgetE(test.Retries, test.Window)
+			if test.Error == "" {
+				assert.NoError(t, err)
+				assert.True(t, bgt.Allow())
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidConfig)
+				assert.EqualError(t, err, test.Error)
+				assert.Nil(t, bgt)
+			}
+		})
+	}
+}
+
+// TestNewBudgetDefaults tests if a budget with an invalid configuration uses
+// safe defaults, where a budget without a positive window never refills
+func TestNewBudgetDefaults(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Retries int
+		Window  time.Duration
+		Allowed int
+	}{
+		{
+			Name:    "Negative retries",
+			Retries: -1,
+			Window:  time.Millisecond,
+			Allowed: 0,
+		},
+		{
+			Name:    "Zero window",
+			Retries: 2,
+			Window:  0,
+			Allowed: 2,
+		},
+		{
+			Name:    "Negative window",
+			Retries: 2,
+			Window:  -time.Second,
+			Allowed: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			bgt := NewBudget(test.Retries, test.Window)
+
+			allowed := 0
+			for i := 0; i < 5; i++ {
+				if bgt.Allow() {
+					allowed++
+				}
+				time.Sleep(time.Millisecond * 2)
+			}
+			assert.Equal(t, test.Allowed, allowed)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package retrier
+
+import "context"
+
+// Chain composes two retriers into a layered policy where the outer retrier
+// wraps the inner one. Each attempt of the outer retrier runs the task with
+// the inner retrier until it succeeds, fails fatally or gives up. When the
+// inner retrier gives up on a task that requested to be retried, the outer
+// retrier sees it as a retryable failure and retries after its own delay. A
+// fatal failure stops both layers.
+//
+// Every attempt of the outer retrier can make up to (inner max + 1) attempts
+// of the task, so a task can be executed up to (outer max + 1) * (inner max
+// + 1) times in total. The options of the outer retrier apply to the layered
+// attempts, while the options of the inner retrier apply to the attempts of
+// the task. The returned retrier is a copy of the outer retrier. If the outer
+// retrier is already chained, the inner retrier is chained under its innermost
+// layer, so no layer is dropped.
+func Chain(outer, inner *Retrier) *Retrier {
+	r := outer.clone()
+	if outer.inner != nil {
+		r.inner = Chain(outer.inner, inner)
+	} else {
+		r.inner = inner
+	}
+	return r
+}
+
+// runTracked executes a work task in the context of a retrier, and reports
+// whether the retrier gave up on a task that still requested to be retried,
+// which distinguishes a task that ran out of retries from a task that failed.
+func (r *Retrier) runTracked(
+	ctx context.Context,
+	work func(ctx context.Context) (error, bool),
+) (error, bool) {
+	err := r.RunCtx(ctx, work)
+	_, exhausted := err.(*ExhaustedError)
+	return err, exhausted
+}
+
+// clone creates a copy of the retrier with the same configuration. The copy
+// tracks its own runs in flight and keeps its own counters.
+func (r *Retrier) clone() *Retrier {
+	c := *r
+	c.cooldowns = append([]cooldownRule(nil), r.cooldowns...)
+	c.observers = append([]Observer(nil), r.observers...)
+	c.interceptors = append([]Interceptor(nil), r.interceptors...)
+	if r.kindPolicies != nil {
+		c.kindPolicies = make(map[OpKind]func(error) bool, len(r.kindPolicies))
+		for kind, policy := range r.kindPolicies {
+			c.kindPolicies[kind] = policy
+		}
+	}
+	c.drain = &drainState{}
+	c.stats = &statsState{}
+	return &c
+}
@@ -0,0 +1,137 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestChain tests if chained retriers run the task with the inner retrier in
+// every attempt of the outer retrier, and if fatal failures stop both layers
+func TestChain(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Task     func(cnt int) (error, bool)
+		Attempts int
+		Elapsed  time.Duration
+		Error    error
+	}{
+		{
+			Name: "Task fails in every layer",
+			Task: func(cnt int) (error, bool) {
+				return fmt.Errorf("error"), true
+			},
+			Attempts: 6,
+			Elapsed:  time.Millisecond * 40,
+			Error: fmt.Errorf(
+				"failed after max retries: failed after max retries: error",
+			),
+		},
+		{
+			Name: "Task succeeds in second outer attempt",
+			Task: func(cnt int) (error, bool) {
+				if cnt < 5 {
+					return fmt.Errorf("error"), true
+				}
+				return nil, false
+			},
+			Attempts: 5,
+			Elapsed:  time.Millisecond * 35,
+			Error:    nil,
+		},
+		{
+			Name: "Task fails fatally",
+			Task: func(cnt int) (error, bool) {
+				if cnt < 2 {
+					return fmt.Errorf("error"), true
+				}
+				return fmt.Errorf("fatal error"), false
+			},
+			Attempts: 2,
+			Elapsed:  time.Millisecond * 5,
+			Error:    fmt.Errorf("fatal error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			outer := NewRetrier(1, ConstantDelay(time.Millisecond*20))
+			inner := NewRetrier(2, ConstantDelay(time.Millisecond*5))
+			retr := Chain(outer, inner)
+
+			cnt := 0
+			var err error
+			ch := make(chan bool)
+			st := time.Now()
+			go func() {
+				err = retr.RunCtx(
+					context.TODO(),
+					func(ctx context.Context) (error, bool) {
+						cnt++
+						return test.Task(cnt)
+					},
+				)
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+			dif := time.Since(st)
+
+			if test.Error != nil {
+				assert.EqualError(t, err, test.Error.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.Attempts, cnt)
+			assert.GreaterOrEqual(t, dif, test.Elapsed)
+		})
+	}
+}
+
+// TestChainLayers tests if chaining a chained retrier keeps every layer, so
+// the attempts of three layers multiply regardless of how they are nested
+func TestChainLayers(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Chain func(a, b, c *Retrier) *Retrier
+	}{
+		{
+			Name: "Chained outer retrier",
+			Chain: func(a, b, c *Retrier) *Retrier {
+				return Chain(Chain(a, b), c)
+			},
+		},
+		{
+			Name: "Chained inner retrier",
+			Chain: func(a, b, c *Retrier) *Retrier {
+				return Chain(a, Chain(b, c))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := test.Chain(
+				NewRetrier(1, NoDelay()),
+				NewRetrier(2, NoDelay()),
+				NewRetrier(3, NoDelay()),
+			)
+
+			cnt := 0
+			err := retr.Run(func() (error, bool) {
+				cnt++
+				return fmt.Errorf("error"), true
+			})
+
+			assert.ErrorIs(t, err, ErrMaxRetriesExceeded)
+			assert.Equal(t, 24, cnt)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package retrier
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is a source of time for the retrier, which measures elapsed time and
+// waits between retries with it. A fake clock makes tests of delays
+// deterministic and instant. Deadlines of contexts are not affected by the
+// clock, since contexts always use the real time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel that receives the current time after the
+	// duration has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// now returns the current time from the clock of the retrier.
+func (r *Retrier) now() time.Time {
+	if r.clock == nil {
+		return time.Now()
+	}
+	return r.clock.Now()
+}
+
+// since returns the time elapsed since some time on the clock of the retrier.
+func (r *Retrier) since(t time.Time) time.Duration {
+	return r.now().Sub(t)
+}
+
+// sleep stops the execution for some duration on the clock of the retrier,
+// or until the context has been canceled or the retrier has been aborted.
+func (r *Retrier) sleep(ctx context.Context, dur time.Duration) error {
+	if r.abort == nil {
+		return r.wait(ctx, dur)
+	}
+
+	sctx, cncl := context.WithCancel(ctx)
+	defer cncl()
+	go func() {
+		select {
+		case <-r.abort:
+			cncl()
+		case <-sctx.Done():
+		}
+	}()
+
+	err := r.wait(sctx, dur)
+	if err != nil && ctx.Err() == nil {
+		return ErrAborted
+	}
+	return err
+}
+
+// wait stops the execution for some duration on the clock of the retrier, or
+// until the context has been canceled.
+func (r *Retrier) wait(ctx context.Context, dur time.Duration) error {
+	if r.clock == nil {
+		return sleep(ctx, dur)
+	}
+
+	select {
+	case <-r.clock.After(dur):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// aborted reports whether the retrier has been aborted.
+func (r *Retrier) aborted() bool {
+	select {
+	case <-r.abort:
+		return true
+	default:
+		return false
+	}
+}
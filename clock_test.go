@@ -0,0 +1,96 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock whose time only advances when waiting on it.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// TestWithClock tests if the retrier waits and measures elapsed time with the
+// clock, so that long delays do not take real time
+func TestWithClock(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Max      int
+		Options  []Option
+		Attempts int
+		Elapsed  time.Duration
+		Error    error
+	}{
+		{
+			Name:     "Exponential delays",
+			Max:      5,
+			Options:  []Option{},
+			Attempts: 6,
+			Elapsed:  time.Hour * 31,
+			Error:    ErrMaxRetriesExceeded,
+		},
+		{
+			Name:     "Max elapsed time",
+			Max:      5,
+			Options:  []Option{WithMaxElapsedTime(time.Hour * 10)},
+			Attempts: 4,
+			Elapsed:  time.Hour * 7,
+			Error:    ErrMaxElapsedTime,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(0, 0)}
+			retr := NewRetrier(
+				test.Max,
+				ExponentialDelay(time.Hour, 2),
+				append(test.Options, WithClock(clock))...,
+			)
+
+			var res Result
+			var err error
+			ch := make(chan bool)
+			go func() {
+				res, err = retr.RunCtxResult(
+					context.TODO(),
+					func(ctx context.Context) (error, bool) {
+						return fmt.Errorf("error"), true
+					},
+				)
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			assert.ErrorIs(t, err, test.Error)
+			assert.Equal(t, test.Attempts, res.Attempts)
+			assert.Equal(t, test.Elapsed, res.Elapsed)
+			assert.Equal(t, time.Unix(0, 0).Add(test.Elapsed), clock.Now())
+		})
+	}
+}
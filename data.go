@@ -0,0 +1,36 @@
+package retrier
+
+import "context"
+
+// Do executes a work task that produces a value with a retrier, and returns
+// the value of the successful attempt. If the task fails, the zero value of
+// the type is returned along with the error.
+func Do[T any](
+	ctx context.Context,
+	r *Retrier,
+	work func(ctx context.Context) (T, error, bool),
+) (T, error) {
+	var val T
+	err := r.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		v, err, retry := work(ctx)
+		if err == nil {
+			val = v
+		}
+		return err, retry
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return val, nil
+}
+
+// RunCtxData executes a work task that produces a payload in the context of a
+// retrier, and returns the payload of the successful attempt. If the task
+// fails, a nil payload is returned along with the error.
+func (r *Retrier) RunCtxData(
+	ctx context.Context,
+	work func(ctx context.Context) (any, error, bool),
+) (any, error) {
+	return Do(ctx, r, work)
+}
@@ -0,0 +1,115 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDo tests if the value of the successful attempt is returned, and the
+// zero value is returned when the task fails
+func TestDo(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Fails int
+		Value int
+		Error error
+	}{
+		{
+			Name:  "Success on first attempt",
+			Fails: 0,
+			Value: 1,
+			Error: nil,
+		},
+		{
+			Name:  "Success after retries",
+			Fails: 2,
+			Value: 3,
+			Error: nil,
+		},
+		{
+			Name:  "Failure after max retries",
+			Fails: 5,
+			Value: 0,
+			Error: fmt.Errorf("failed after max retries: error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, ConstantDelay(time.Millisecond))
+
+			attempts := 0
+			val, err := Do(
+				context.TODO(),
+				retr,
+				func(ctx context.Context) (int, error, bool) {
+					attempts++
+					if attempts <= test.Fails {
+						return attempts, fmt.Errorf("error"), true
+					}
+					return attempts, nil, false
+				},
+			)
+
+			assert.Equal(t, test.Value, val)
+			if test.Error != nil {
+				assert.EqualError(t, err, test.Error.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestRunCtxData tests if the payload of the successful attempt is returned,
+// and a nil payload is returned when the task fails
+func TestRunCtxData(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Fails   int
+		Payload any
+		Error   error
+	}{
+		{
+			Name:    "Success after retries",
+			Fails:   1,
+			Payload: "payload",
+			Error:   nil,
+		},
+		{
+			Name:    "Failure after max retries",
+			Fails:   5,
+			Payload: nil,
+			Error:   fmt.Errorf("failed after max retries: error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, ConstantDelay(time.Millisecond))
+
+			attempts := 0
+			payload, err := retr.RunCtxData(
+				context.TODO(),
+				func(ctx context.Context) (any, error, bool) {
+					attempts++
+					if attempts <= test.Fails {
+						return "partial", fmt.Errorf("error"), true
+					}
+					return "payload", nil, false
+				},
+			)
+
+			assert.Equal(t, test.Payload, payload)
+			if test.Error != nil {
+				assert.EqualError(t, err, test.Error.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,493 @@
+package retrier
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// toDuration converts a number of nanoseconds to a duration, clamping it
+// between zero and the longest representable duration.
+func toDuration(ns float64) time.Duration {
+	if ns <= 0 || math.IsNaN(ns) {
+		return 0
+	} else if ns >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(ns)
+}
+
+// PoissonDelay returns a delay function that draws delays from an exponential
+// distribution with some mean, which models the inter-arrival times of a
+// Poisson process. The delay is calculated by (-mean*ln(1-U)) where U is a
+// uniformly random number in [0, 1) generated from the source. If the source
+// is nil, the default source is used.
+//
+// The delays have a mean and a standard deviation equal to the configured mean,
+// and are never negative. Short delays are more likely than long ones, but
+// there is no upper bound. See CappedPoissonDelay to limit the delays.
+func PoissonDelay(
+	mean time.Duration,
+	src rand.Source,
+) DelayFunc {
+	rnd := newRand(src)
+	return func(retries int) time.Duration {
+		return toDuration(-float64(mean) * math.Log(1-rnd()))
+	}
+}
+
+// CappedPoissonDelay returns a delay function that draws delays from an
+// exponential distribution with some mean, up to a specific limit where delay
+// can not be longer. The delay is calculated by min(-mean*ln(1-U), cap).
+// Capping the delays lowers their mean below the configured mean.
+func CappedPoissonDelay(
+	mean time.Duration,
+	cap time.Duration,
+	src rand.Source,
+) DelayFunc {
+	delayf := PoissonDelay(mean, src)
+	return func(retries int) time.Duration {
+		delay := delayf(retries)
+		if delay < cap {
+			return delay
+		} else {
+			return cap
+		}
+	}
+}
+
+// cappedExponential computes (base*2^retries) capped at a limit, without
+// overflowing for large numbers of retries.
+func cappedExponential(
+	base time.Duration,
+	cap time.Duration,
+	retries int,
+) time.Duration {
+	delay := float64(base) * math.Pow(2, float64(retries))
+	if delay < float64(cap) {
+		return toDuration(delay)
+	} else {
+		return cap
+	}
+}
+
+// FullJitterDelay returns a delay function that creates a uniformly random
+// wait duration between zero and an exponentially increasing upper bound that
+// is capped at a specific limit. The delay is calculated by
+// rand(0, min(base*2^retries, cap)). Randomizing the whole delay prevents many
+// clients that failed at the same time from retrying in sync.
+func FullJitterDelay(
+	base time.Duration,
+	cap time.Duration,
+) DelayFunc {
+	rnd := newRand(nil)
+	return func(retries int) time.Duration {
+		bound := cappedExponential(base, cap, retries)
+		return toDuration(rnd() * float64(bound))
+	}
+}
+
+// EqualJitterDelay returns a delay function that creates a wait duration of
+// half of an exponentially increasing delay capped at a specific limit, plus a
+// uniformly random duration up to the other half. The delay is calculated by
+// (d/2 + rand(0, d/2)) where d is min(base*2^retries, cap). This guarantees a
+// minimum wait while still preventing clients from retrying in sync.
+func EqualJitterDelay(
+	base time.Duration,
+	cap time.Duration,
+) DelayFunc {
+	rnd := newRand(nil)
+	return func(retries int) time.Duration {
+		half := cappedExponential(base, cap, retries) / 2
+		return half + toDuration(rnd()*float64(half))
+	}
+}
+
+// DecorrelatedJitterDelay returns a delay function that creates a random wait
+// duration based on the previous delay, up to a specific limit where delay can
+// not be longer. The delay is calculated by min(rand(base, prev*3), cap) where
+// prev is the previous delay, starting from the base.
+//
+// The delay function is stateful, since it remembers the previous delay. The
+// state is reset when it is called with zero retries at the start of a run.
+// Concurrent runs that share the delay function interleave their state, so
+// each concurrent run should use its own delay function.
+func DecorrelatedJitterDelay(
+	base time.Duration,
+	cap time.Duration,
+) DelayFunc {
+	rnd := newRand(nil)
+	mu := sync.Mutex{}
+	prev := base
+	return func(retries int) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if retries == 0 {
+			prev = base
+		}
+		upper := math.Min(float64(prev)*3, float64(cap))
+		delay := toDuration(float64(base) + rnd()*(upper-float64(base)))
+		if delay > cap {
+			delay = cap
+		}
+		prev = delay
+		return delay
+	}
+}
+
+// fibonacci computes (unit*F(retries+2)) where F is the Fibonacci sequence,
+// clamping the result to the longest representable duration.
+func fibonacci(unit time.Duration, retries int) time.Duration {
+	a, b := float64(unit), float64(unit)*2
+	for i := 0; i < retries && a < math.MaxInt64; i++ {
+		a, b = b, a+b
+	}
+	return toDuration(a)
+}
+
+// FibonacciDelay returns a delay function that creates a wait duration that
+// grows following the Fibonacci sequence, which is a middle ground between
+// linear and exponential growth. The delay is calculated by (unit*F(r)) where
+// F(r) is the sequence 1, 2, 3, 5, 8, 13...
+func FibonacciDelay(
+	unit time.Duration,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		return fibonacci(unit, retries)
+	}
+}
+
+// CappedFibonacciDelay returns a delay function that creates a wait duration
+// that grows following the Fibonacci sequence up to a specific limit where
+// delay can not be longer. The delay is calculated by min(unit*F(r), cap).
+func CappedFibonacciDelay(
+	unit time.Duration,
+	cap time.Duration,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		delay := fibonacci(unit, retries)
+		if delay < cap {
+			return delay
+		} else {
+			return cap
+		}
+	}
+}
+
+// PolynomialDelay returns a delay function that creates a wait duration that
+// grows polynomially between retries, which is faster than linear but slower
+// than exponential growth for exponents above 1. The delay is calculated by
+// (unit*(retries+1)^exponent), so the first delay is the unit like in
+// LinearDelay.
+func PolynomialDelay(
+	unit time.Duration,
+	exponent float64,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		scale := math.Pow(float64(retries+1), exponent)
+		return toDuration(float64(unit) * scale)
+	}
+}
+
+// RandomDelay returns a delay function that creates a uniformly random wait
+// duration between a minimum and a maximum for every retry, generated from the
+// source. If the source is nil, the default source is used.
+func RandomDelay(
+	min time.Duration,
+	max time.Duration,
+	src rand.Source,
+) DelayFunc {
+	rnd := newRand(src)
+	return func(retries int) time.Duration {
+		return min + toDuration(rnd()*float64(max-min))
+	}
+}
+
+// ScheduleDelay returns a delay function that follows an explicit schedule of
+// delays, such as the retry schedules that many services recommend. The delay
+// is the nth delay of the schedule for n retries, and the last delay repeats
+// once the schedule runs out. An empty schedule has no delay.
+func ScheduleDelay(
+	delays ...time.Duration,
+) DelayFunc {
+	delays = append([]time.Duration(nil), delays...)
+	return func(retries int) time.Duration {
+		if len(delays) == 0 {
+			return 0
+		} else if retries < len(delays) {
+			return delays[retries]
+		} else {
+			return delays[len(delays)-1]
+		}
+	}
+}
+
+// MaxOf returns a delay function that creates the longest wait duration out
+// of multiple delay functions, which can be used to put a floor under another
+// delay function. Without any delay functions there is no delay.
+func MaxOf(
+	delayfs ...DelayFunc,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		delay := time.Duration(0)
+		for i, delayf := range delayfs {
+			if d := delayf(retries); i == 0 || d > delay {
+				delay = d
+			}
+		}
+		return delay
+	}
+}
+
+// MinOf returns a delay function that creates the shortest wait duration out
+// of multiple delay functions, which can be used to put a ceiling over another
+// delay function. Without any delay functions there is no delay.
+func MinOf(
+	delayfs ...DelayFunc,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		delay := time.Duration(0)
+		for i, delayf := range delayfs {
+			if d := delayf(retries); i == 0 || d < delay {
+				delay = d
+			}
+		}
+		return delay
+	}
+}
+
+// Sum returns a delay function that creates the sum of the wait durations of
+// multiple delay functions, clamped to the longest representable duration.
+// Without any delay functions there is no delay.
+func Sum(
+	delayfs ...DelayFunc,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		delay := float64(0)
+		for _, delayf := range delayfs {
+			delay += float64(delayf(retries))
+		}
+		return toDuration(delay)
+	}
+}
+
+// ExponentialFactorDelay returns a delay function that creates an
+// exponentially increasing wait duration between retries with a fractional
+// growth factor, such as 1.5. The delay is calculated by
+// (initial*factor^retries).
+func ExponentialFactorDelay(
+	initial time.Duration,
+	factor float64,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		scale := math.Pow(factor, float64(retries))
+		return toDuration(float64(initial) * scale)
+	}
+}
+
+// CappedExponentialFactorDelay returns a delay function that creates an
+// exponentially increasing wait duration between retries with a fractional
+// growth factor up to a specific limit where delay can not be longer. The
+// delay is calculated by min(initial*factor^retries, cap).
+func CappedExponentialFactorDelay(
+	initial time.Duration,
+	factor float64,
+	cap time.Duration,
+) DelayFunc {
+	delayf := ExponentialFactorDelay(initial, factor)
+	return func(retries int) time.Duration {
+		delay := delayf(retries)
+		if delay < cap {
+			return delay
+		} else {
+			return cap
+		}
+	}
+}
+
+// NormalDelay returns a delay function that draws delays from a normal
+// distribution with some mean and standard deviation, generated from the
+// source. If the source is nil, the default source is used. Delays that would
+// be negative are clamped to zero, which raises the mean slightly when the
+// standard deviation is large compared to the mean. For an exponential
+// distribution, see PoissonDelay.
+func NormalDelay(
+	mean time.Duration,
+	stddev time.Duration,
+	src rand.Source,
+) DelayFunc {
+	rnd := newNormRand(src)
+	return func(retries int) time.Duration {
+		return toDuration(float64(mean) + rnd()*float64(stddev))
+	}
+}
+
+// LogNormalDelay returns a delay function that draws delays from a log-normal
+// distribution with some mean, generated from the source. If the source is nil,
+// the default source is used. The shape is the standard deviation of the
+// logarithm of the delays, where larger values produce a longer tail of rare
+// but long delays. The delays are never negative.
+func LogNormalDelay(
+	mean time.Duration,
+	shape float64,
+	src rand.Source,
+) DelayFunc {
+	rnd := newNormRand(src)
+	mu := math.Log(float64(mean)) - shape*shape/2
+	return func(retries int) time.Duration {
+		return toDuration(math.Exp(mu + rnd()*shape))
+	}
+}
+
+// WarmupDelay returns a delay function that retries quickly with a short
+// constant delay for the first few retries, then hands off to another delay
+// function. The other delay function is called with the number of retries
+// since the hand-off, so it starts from its first delay.
+func WarmupDelay(
+	fast time.Duration,
+	fastAttempts int,
+	slow DelayFunc,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		if retries < fastAttempts {
+			return fast
+		} else {
+			return slow(retries - fastAttempts)
+		}
+	}
+}
+
+// DecayingDelay returns a delay function that creates a decreasing wait
+// duration between retries down to a specific floor where delay can not be
+// shorter. The delay is calculated by max(start*factor^retries, floor), where
+// the factor is between 0 and 1.
+func DecayingDelay(
+	start time.Duration,
+	floor time.Duration,
+	factor float64,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		scale := math.Pow(factor, float64(retries))
+		delay := toDuration(float64(start) * scale)
+		if delay > floor {
+			return delay
+		} else {
+			return floor
+		}
+	}
+}
+
+// AlignedDelay returns a delay function that creates a wait duration until
+// the next wall-clock boundary of an interval, such as the top of the minute,
+// rather than a relative duration. This suits services whose quotas reset at
+// fixed times. Boundaries are multiples of the interval since the zero time,
+// and a retry that falls exactly on a boundary waits for the next one.
+//
+// The current time is read from the clock, which should be the clock of the
+// retrier set with WithClock, so that fake clocks align the delays too. If the
+// clock is nil, the real time is used.
+func AlignedDelay(
+	interval time.Duration,
+	clock Clock,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		now := time.Now()
+		if clock != nil {
+			now = clock.Now()
+		}
+		return now.Truncate(interval).Add(interval).Sub(now)
+	}
+}
+
+// harmonic computes the nth harmonic number H(n) = 1 + 1/2 + ... + 1/n, using
+// an asymptotic expansion for large numbers to avoid summing every term.
+func harmonic(n int) float64 {
+	if n > 1000 {
+		x := float64(n)
+		return math.Log(x) + 0.5772156649015329 + 1/(2*x) - 1/(12*x*x)
+	}
+
+	h := float64(0)
+	for k := 1; k <= n; k++ {
+		h += 1 / float64(k)
+	}
+	return h
+}
+
+// HarmonicDelay returns a delay function that creates a wait duration that
+// grows with the harmonic series, which is slower than linear growth and
+// suits long polling loops over hundreds of retries. The delay is calculated
+// by (unit*H(retries+1)) where H(n) is 1 + 1/2 + ... + 1/n.
+func HarmonicDelay(
+	unit time.Duration,
+) DelayFunc {
+	return func(retries int) time.Duration {
+		return toDuration(float64(unit) * harmonic(retries+1))
+	}
+}
+
+// Stage is a stage of a staged delay policy, which covers some number of
+// retries with its own delay function.
+type Stage struct {
+	// Retries is the number of retries that the stage covers. The last stage
+	// covers all remaining retries regardless of the value.
+	Retries int
+
+	// Delay is the delay function of the stage. It is called with the number
+	// of retries since the start of the stage.
+	Delay DelayFunc
+}
+
+// StagedDelay returns a delay function that goes through stages of delay
+// functions, each covering some number of retries, such as a constant delay
+// for the first few retries, then an exponential delay, then a long constant
+// delay for all remaining retries. Without any stages there is no delay.
+func StagedDelay(
+	stages ...Stage,
+) DelayFunc {
+	stages = append([]Stage(nil), stages...)
+	return func(retries int) time.Duration {
+		for i, stage := range stages {
+			if retries < stage.Retries || i == len(stages)-1 {
+				return stage.Delay(retries)
+			}
+			retries -= stage.Retries
+		}
+		return 0
+	}
+}
+
+// WithJitter returns a delay function that randomly perturbs the delays of
+// another delay function by up to some fraction of the delay in either
+// direction. The delay is calculated by (d*(1+rand(-fraction, fraction)))
+// where d is the delay of the other delay function.
+func WithJitter(
+	delayf DelayFunc,
+	fraction float64,
+) DelayFunc {
+	rnd := newRand(nil)
+	return func(retries int) time.Duration {
+		return jitter(delayf(retries), fraction, rnd())
+	}
+}
+
+// CyclicDelay returns a delay function that creates an exponentially
+// increasing wait duration up to a specific limit, then starts over from the
+// base, repeating in cycles. For tasks that retry indefinitely, this probes
+// aggressively from time to time while still backing off most of the time.
+// The delay is calculated by min(base*2^(retries mod n), cap) where n is the
+// number of delays it takes to reach the cap.
+func CyclicDelay(
+	base time.Duration,
+	cap time.Duration,
+) DelayFunc {
+	n := 1
+	for d := base; d > 0 && d < cap; d *= 2 {
+		n++
+	}
+	return func(retries int) time.Duration {
+		return cappedExponential(base, cap, retries%n)
+	}
+}
@@ -0,0 +1,1114 @@
+package retrier
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPoissonDelay tests if the poisson delay function returns non-negative
+// delays whose empirical mean approximates the configured mean
+func TestPoissonDelay(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Mean    time.Duration
+		Samples int
+	}{
+		{
+			Name:    "Mean of one second",
+			Mean:    time.Second,
+			Samples: 100000,
+		},
+		{
+			Name:    "Mean of one millisecond",
+			Mean:    time.Millisecond,
+			Samples: 100000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := PoissonDelay(test.Mean, rand.NewSource(1))
+
+			sum := time.Duration(0)
+			for i := 0; i < test.Samples; i++ {
+				dur := fn(i)
+				assert.GreaterOrEqual(t, dur, time.Duration(0))
+				sum += dur
+			}
+			mean := sum / time.Duration(test.Samples)
+
+			assert.InEpsilon(t, float64(test.Mean), float64(mean), 0.02)
+		})
+	}
+}
+
+// TestCappedPoissonDelay tests if the capped poisson delay function returns
+// delays that are never longer than the limit
+func TestCappedPoissonDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Mean     time.Duration
+		DelayCap time.Duration
+		Samples  int
+	}{
+		{
+			Name:     "Cap below mean",
+			Mean:     time.Second,
+			DelayCap: time.Millisecond * 500,
+			Samples:  1000,
+		},
+		{
+			Name:     "Cap above mean",
+			Mean:     time.Second,
+			DelayCap: time.Second * 2,
+			Samples:  1000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := CappedPoissonDelay(
+				test.Mean,
+				test.DelayCap,
+				rand.NewSource(1),
+			)
+
+			capped := false
+			for i := 0; i < test.Samples; i++ {
+				dur := fn(i)
+				assert.GreaterOrEqual(t, dur, time.Duration(0))
+				assert.LessOrEqual(t, dur, test.DelayCap)
+				capped = capped || dur == test.DelayCap
+			}
+
+			assert.True(t, capped)
+		})
+	}
+}
+
+// TestFullJitterDelay tests if the full jitter delay function returns delays
+// between zero and the capped exponential upper bound
+func TestFullJitterDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Base     time.Duration
+		DelayCap time.Duration
+		Bound    time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			Base:     time.Second,
+			DelayCap: time.Minute,
+			Bound:    time.Second,
+		},
+		{
+			Name:     "Third call",
+			Count:    2,
+			Base:     time.Second,
+			DelayCap: time.Minute,
+			Bound:    time.Second * 4,
+		},
+		{
+			Name:     "Nth call outside limit",
+			Count:    25,
+			Base:     time.Second,
+			DelayCap: time.Minute,
+			Bound:    time.Minute,
+		},
+		{
+			Name:     "Nth call that would overflow",
+			Count:    1000,
+			Base:     time.Second,
+			DelayCap: time.Minute,
+			Bound:    time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := FullJitterDelay(test.Base, test.DelayCap)
+
+			for i := 0; i < 100; i++ {
+				dur := fn(test.Count)
+				assert.GreaterOrEqual(t, dur, time.Duration(0))
+				assert.Less(t, dur, test.Bound)
+			}
+		})
+	}
+}
+
+// TestEqualJitterDelay tests if the equal jitter delay function returns delays
+// between half of the capped exponential delay and the full delay
+func TestEqualJitterDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Base     time.Duration
+		DelayCap time.Duration
+		Bound    time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			Base:     time.Second,
+			DelayCap: time.Minute,
+			Bound:    time.Second,
+		},
+		{
+			Name:     "Third call",
+			Count:    2,
+			Base:     time.Second,
+			DelayCap: time.Minute,
+			Bound:    time.Second * 4,
+		},
+		{
+			Name:     "Nth call outside limit",
+			Count:    25,
+			Base:     time.Second,
+			DelayCap: time.Minute,
+			Bound:    time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := EqualJitterDelay(test.Base, test.DelayCap)
+
+			for i := 0; i < 100; i++ {
+				dur := fn(test.Count)
+				assert.GreaterOrEqual(t, dur, test.Bound/2)
+				assert.Less(t, dur, test.Bound)
+			}
+		})
+	}
+}
+
+// TestDecorrelatedJitterDelay tests if the decorrelated jitter delay function
+// returns delays between the base and three times the previous delay, up to
+// the limit, and restarts from the base at the start of a run
+func TestDecorrelatedJitterDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Base     time.Duration
+		DelayCap time.Duration
+		Runs     int
+		Count    int
+	}{
+		{
+			Name:     "Single run",
+			Base:     time.Second,
+			DelayCap: time.Minute,
+			Runs:     1,
+			Count:    50,
+		},
+		{
+			Name:     "Multiple runs",
+			Base:     time.Second,
+			DelayCap: time.Minute,
+			Runs:     5,
+			Count:    10,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := DecorrelatedJitterDelay(test.Base, test.DelayCap)
+
+			for r := 0; r < test.Runs; r++ {
+				prev := test.Base
+				for i := 0; i < test.Count; i++ {
+					dur := fn(i)
+					assert.GreaterOrEqual(t, dur, test.Base)
+					assert.LessOrEqual(t, dur, prev*3)
+					assert.LessOrEqual(t, dur, test.DelayCap)
+					prev = dur
+				}
+			}
+		})
+	}
+}
+
+// TestFibonacciDelay tests if the fibonacci delay function returns delays
+// that grow following the Fibonacci sequence
+func TestFibonacciDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Unit     time.Duration
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			Unit:     time.Second,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Second call",
+			Count:    1,
+			Unit:     time.Second,
+			DelayOut: time.Second * 2,
+		},
+		{
+			Name:     "Fifth call",
+			Count:    4,
+			Unit:     time.Second,
+			DelayOut: time.Second * 8,
+		},
+		{
+			Name:     "Nth call",
+			Count:    20,
+			Unit:     time.Second,
+			DelayOut: time.Second * 17711,
+		},
+		{
+			Name:     "Nth call that would overflow",
+			Count:    1000,
+			Unit:     time.Second,
+			DelayOut: time.Duration(math.MaxInt64),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := FibonacciDelay(test.Unit)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestCappedFibonacciDelay tests if the capped fibonacci delay function
+// returns delays that grow following the Fibonacci sequence until they reach
+// the limit
+func TestCappedFibonacciDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Unit     time.Duration
+		DelayCap time.Duration
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			Unit:     time.Second,
+			DelayCap: time.Second * 10,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Nth call within limit",
+			Count:    4,
+			Unit:     time.Second,
+			DelayCap: time.Second * 10,
+			DelayOut: time.Second * 8,
+		},
+		{
+			Name:     "Nth call outside of limit",
+			Count:    5,
+			Unit:     time.Second,
+			DelayCap: time.Second * 10,
+			DelayOut: time.Second * 10,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := CappedFibonacciDelay(test.Unit, test.DelayCap)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestPolynomialDelay tests if the polynomial delay function returns the unit
+// delay on the first call, then grows the delay by the power of the exponent
+func TestPolynomialDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Unit     time.Duration
+		Exponent float64
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			Unit:     time.Second,
+			Exponent: 2,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Quadratic growth",
+			Count:    3,
+			Unit:     time.Second,
+			Exponent: 2,
+			DelayOut: time.Second * 16,
+		},
+		{
+			Name:     "Cubic growth",
+			Count:    2,
+			Unit:     time.Second,
+			Exponent: 3,
+			DelayOut: time.Second * 27,
+		},
+		{
+			Name:     "Fractional exponent",
+			Count:    3,
+			Unit:     time.Second,
+			Exponent: 0.5,
+			DelayOut: time.Second * 2,
+		},
+		{
+			Name:     "Nth call that would overflow",
+			Count:    1000000,
+			Unit:     time.Second,
+			Exponent: 5,
+			DelayOut: time.Duration(math.MaxInt64),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := PolynomialDelay(test.Unit, test.Exponent)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestRandomDelay tests if the random delay function returns delays between
+// the minimum and the maximum, and the same delays for the same source
+func TestRandomDelay(t *testing.T) {
+	tests := []struct {
+		Name string
+		Min  time.Duration
+		Max  time.Duration
+	}{
+		{
+			Name: "Range of delays",
+			Min:  time.Second,
+			Max:  time.Second * 5,
+		},
+		{
+			Name: "Single delay",
+			Min:  time.Second,
+			Max:  time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := RandomDelay(test.Min, test.Max, rand.NewSource(1))
+			cmp := RandomDelay(test.Min, test.Max, rand.NewSource(1))
+
+			for i := 0; i < 100; i++ {
+				dur := fn(i)
+				assert.GreaterOrEqual(t, dur, test.Min)
+				assert.LessOrEqual(t, dur, test.Max)
+				assert.Equal(t, cmp(i), dur)
+			}
+		})
+	}
+}
+
+// TestScheduleDelay tests if the schedule delay function returns the delays
+// of the schedule in order, and repeats the last delay after the schedule
+func TestScheduleDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Schedule []time.Duration
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			Schedule: []time.Duration{time.Second, time.Second * 5},
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Last call of schedule",
+			Count:    1,
+			Schedule: []time.Duration{time.Second, time.Second * 5},
+			DelayOut: time.Second * 5,
+		},
+		{
+			Name:     "Nth call after schedule",
+			Count:    25,
+			Schedule: []time.Duration{time.Second, time.Second * 5},
+			DelayOut: time.Second * 5,
+		},
+		{
+			Name:     "Empty schedule",
+			Count:    0,
+			Schedule: nil,
+			DelayOut: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := ScheduleDelay(test.Schedule...)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestMaxOf tests if the max of delay function returns the longest delay of
+// the delay functions
+func TestMaxOf(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Delays   []DelayFunc
+		DelayOut time.Duration
+	}{
+		{
+			Name:  "Floor applies",
+			Count: 0,
+			Delays: []DelayFunc{
+				ExponentialDelay(time.Second, 2),
+				ConstantDelay(time.Second * 3),
+			},
+			DelayOut: time.Second * 3,
+		},
+		{
+			Name:  "Floor is exceeded",
+			Count: 3,
+			Delays: []DelayFunc{
+				ExponentialDelay(time.Second, 2),
+				ConstantDelay(time.Second * 3),
+			},
+			DelayOut: time.Second * 8,
+		},
+		{
+			Name:     "No delay functions",
+			Count:    0,
+			Delays:   nil,
+			DelayOut: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := MaxOf(test.Delays...)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestMinOf tests if the min of delay function returns the shortest delay of
+// the delay functions
+func TestMinOf(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Delays   []DelayFunc
+		DelayOut time.Duration
+	}{
+		{
+			Name:  "Ceiling is not reached",
+			Count: 0,
+			Delays: []DelayFunc{
+				ExponentialDelay(time.Second, 2),
+				ConstantDelay(time.Second * 3),
+			},
+			DelayOut: time.Second,
+		},
+		{
+			Name:  "Ceiling applies",
+			Count: 3,
+			Delays: []DelayFunc{
+				ExponentialDelay(time.Second, 2),
+				ConstantDelay(time.Second * 3),
+			},
+			DelayOut: time.Second * 3,
+		},
+		{
+			Name:     "No delay functions",
+			Count:    0,
+			Delays:   nil,
+			DelayOut: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := MinOf(test.Delays...)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestSum tests if the sum delay function returns the sum of the delays of
+// the delay functions
+func TestSum(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Delays   []DelayFunc
+		DelayOut time.Duration
+	}{
+		{
+			Name:  "Sum of delays",
+			Count: 3,
+			Delays: []DelayFunc{
+				ExponentialDelay(time.Second, 2),
+				ConstantDelay(time.Second * 3),
+			},
+			DelayOut: time.Second * 11,
+		},
+		{
+			Name:  "Sum that would overflow",
+			Count: 0,
+			Delays: []DelayFunc{
+				ConstantDelay(time.Duration(math.MaxInt64)),
+				ConstantDelay(time.Second),
+			},
+			DelayOut: time.Duration(math.MaxInt64),
+		},
+		{
+			Name:     "No delay functions",
+			Count:    0,
+			Delays:   nil,
+			DelayOut: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := Sum(test.Delays...)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestExponentialFactorDelay tests if the exponential factor delay function
+// returns the initial delay on the first call, then grows the delay by the
+// fractional factor for each subsequent call
+func TestExponentialFactorDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Factor   float64
+		DelayIn  time.Duration
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			Factor:   1.5,
+			DelayIn:  time.Second,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Third call",
+			Count:    2,
+			Factor:   1.5,
+			DelayIn:  time.Second,
+			DelayOut: time.Millisecond * 2250,
+		},
+		{
+			Name:     "Nth call that would overflow",
+			Count:    1000,
+			Factor:   1.5,
+			DelayIn:  time.Second,
+			DelayOut: time.Duration(math.MaxInt64),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := ExponentialFactorDelay(test.DelayIn, test.Factor)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestCappedExponentialFactorDelay tests if the capped exponential factor
+// delay function grows the delay by the fractional factor until it reaches
+// the limit
+func TestCappedExponentialFactorDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Factor   float64
+		DelayIn  time.Duration
+		DelayCap time.Duration
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			Factor:   1.5,
+			DelayIn:  time.Second,
+			DelayCap: time.Second * 10,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Nth call within limit",
+			Count:    3,
+			Factor:   1.5,
+			DelayIn:  time.Second,
+			DelayCap: time.Second * 10,
+			DelayOut: time.Millisecond * 3375,
+		},
+		{
+			Name:     "Nth call outside limit",
+			Count:    1000,
+			Factor:   1.5,
+			DelayIn:  time.Second,
+			DelayCap: time.Second * 10,
+			DelayOut: time.Second * 10,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := CappedExponentialFactorDelay(
+				test.DelayIn,
+				test.Factor,
+				test.DelayCap,
+			)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestNormalDelay tests if the normal delay function returns non-negative
+// delays whose empirical mean approximates the configured mean
+func TestNormalDelay(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Mean    time.Duration
+		StdDev  time.Duration
+		Samples int
+	}{
+		{
+			Name:    "Narrow distribution",
+			Mean:    time.Second,
+			StdDev:  time.Millisecond * 100,
+			Samples: 100000,
+		},
+		{
+			Name:    "Wide distribution",
+			Mean:    time.Second,
+			StdDev:  time.Millisecond * 300,
+			Samples: 100000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := NormalDelay(test.Mean, test.StdDev, rand.NewSource(1))
+
+			sum := time.Duration(0)
+			for i := 0; i < test.Samples; i++ {
+				dur := fn(i)
+				assert.GreaterOrEqual(t, dur, time.Duration(0))
+				sum += dur
+			}
+			mean := sum / time.Duration(test.Samples)
+
+			assert.InEpsilon(t, float64(test.Mean), float64(mean), 0.02)
+		})
+	}
+}
+
+// TestLogNormalDelay tests if the log-normal delay function returns
+// non-negative delays whose empirical mean approximates the configured mean
+func TestLogNormalDelay(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Mean    time.Duration
+		Shape   float64
+		Samples int
+	}{
+		{
+			Name:    "Short tail",
+			Mean:    time.Second,
+			Shape:   0.25,
+			Samples: 100000,
+		},
+		{
+			Name:    "Long tail",
+			Mean:    time.Second,
+			Shape:   0.75,
+			Samples: 100000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := LogNormalDelay(test.Mean, test.Shape, rand.NewSource(1))
+
+			sum := time.Duration(0)
+			for i := 0; i < test.Samples; i++ {
+				dur := fn(i)
+				assert.GreaterOrEqual(t, dur, time.Duration(0))
+				sum += dur
+			}
+			mean := sum / time.Duration(test.Samples)
+
+			assert.InEpsilon(t, float64(test.Mean), float64(mean), 0.02)
+		})
+	}
+}
+
+// TestWarmupDelay tests if the warmup delay function returns the fast delay
+// for the first retries, then the delays of the slow delay function starting
+// from its first delay
+func TestWarmupDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			DelayOut: time.Millisecond * 10,
+		},
+		{
+			Name:     "Last fast call",
+			Count:    2,
+			DelayOut: time.Millisecond * 10,
+		},
+		{
+			Name:     "First slow call",
+			Count:    3,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Nth slow call",
+			Count:    6,
+			DelayOut: time.Second * 8,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := WarmupDelay(
+				time.Millisecond*10,
+				3,
+				ExponentialDelay(time.Second, 2),
+			)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestDecayingDelay tests if the decaying delay function returns the start
+// delay on the first call, then decreases the delay until it reaches the floor
+func TestDecayingDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			DelayOut: time.Second * 8,
+		},
+		{
+			Name:     "Second call",
+			Count:    1,
+			DelayOut: time.Second * 4,
+		},
+		{
+			Name:     "Nth call above floor",
+			Count:    2,
+			DelayOut: time.Second * 2,
+		},
+		{
+			Name:     "Nth call below floor",
+			Count:    25,
+			DelayOut: time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := DecayingDelay(time.Second*8, time.Second, 0.5)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestAlignedDelay tests if the aligned delay function returns delays that end
+// on the next boundary of the interval, measured on the real time or a clock
+func TestAlignedDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Interval time.Duration
+		Clock    *fakeClock
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "Second boundary",
+			Interval: time.Second,
+		},
+		{
+			Name:     "Minute boundary",
+			Interval: time.Minute,
+		},
+		{
+			Name:     "Minute boundary on a clock",
+			Interval: time.Minute,
+			Clock:    &fakeClock{now: time.Unix(90, 0)},
+			DelayOut: time.Second * 30,
+		},
+		{
+			Name:     "Exact boundary on a clock",
+			Interval: time.Minute,
+			Clock:    &fakeClock{now: time.Unix(120, 0)},
+			DelayOut: time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if test.Clock != nil {
+				fn := AlignedDelay(test.Interval, test.Clock)
+				assert.Equal(t, test.DelayOut, fn(0))
+				return
+			}
+
+			fn := AlignedDelay(test.Interval, nil)
+
+			dur := fn(0)
+			end := time.Now().Add(dur)
+			off := end.Sub(end.Round(test.Interval))
+
+			assert.Greater(t, dur, time.Duration(0))
+			assert.LessOrEqual(t, dur, test.Interval)
+			assert.InDelta(t, 0, float64(off), float64(time.Millisecond*5))
+		})
+	}
+}
+
+// TestHarmonicDelay tests if the harmonic delay function returns the unit
+// delay on the first call, then grows the delay with the harmonic series
+func TestHarmonicDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Unit     time.Duration
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			Unit:     time.Second,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Second call",
+			Count:    1,
+			Unit:     time.Second,
+			DelayOut: time.Millisecond * 1500,
+		},
+		{
+			Name:     "Fourth call",
+			Count:    3,
+			Unit:     time.Second * 12,
+			DelayOut: time.Second * 25,
+		},
+		{
+			Name:     "Nth call",
+			Count:    9999,
+			Unit:     time.Second,
+			DelayOut: time.Duration(9.787606036 * float64(time.Second)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := HarmonicDelay(test.Unit)
+			dur := fn(test.Count)
+
+			assert.InDelta(t, float64(test.DelayOut), float64(dur), 1000)
+		})
+	}
+}
+
+// TestStagedDelay tests if the staged delay function returns the delays of
+// the stage that covers the retry, counting retries from the start of the stage
+func TestStagedDelay(t *testing.T) {
+	stages := []Stage{
+		{
+			Retries: 4,
+			Delay:   ConstantDelay(time.Millisecond * 100),
+		},
+		{
+			Retries: 7,
+			Delay:   ExponentialDelay(time.Second, 2),
+		},
+		{
+			Delay: ConstantDelay(time.Minute * 5),
+		},
+	}
+
+	tests := []struct {
+		Name     string
+		Count    int
+		Stages   []Stage
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First stage",
+			Count:    3,
+			Stages:   stages,
+			DelayOut: time.Millisecond * 100,
+		},
+		{
+			Name:     "Start of second stage",
+			Count:    4,
+			Stages:   stages,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "End of second stage",
+			Count:    10,
+			Stages:   stages,
+			DelayOut: time.Second * 64,
+		},
+		{
+			Name:     "Last stage",
+			Count:    25,
+			Stages:   stages,
+			DelayOut: time.Minute * 5,
+		},
+		{
+			Name:     "No stages",
+			Count:    0,
+			Stages:   nil,
+			DelayOut: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := StagedDelay(test.Stages...)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
+
+// TestWithJitter tests if the jitter wrapper returns delays of the wrapped
+// delay function perturbed within the fraction
+func TestWithJitter(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		Delay    func(int) time.Duration
+		Fraction float64
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "Jittered linear delay",
+			Count:    2,
+			Delay:    LinearDelay(time.Second),
+			Fraction: 0.2,
+			DelayOut: time.Second * 3,
+		},
+		{
+			Name:     "Jittered schedule delay",
+			Count:    1,
+			Delay:    ScheduleDelay(time.Second, time.Second*5),
+			Fraction: 0.5,
+			DelayOut: time.Second * 5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := WithJitter(test.Delay, test.Fraction)
+			margin := time.Duration(float64(test.DelayOut) * test.Fraction)
+
+			changed := false
+			for i := 0; i < 100; i++ {
+				dur := fn(test.Count)
+				assert.GreaterOrEqual(t, dur, test.DelayOut-margin)
+				assert.LessOrEqual(t, dur, test.DelayOut+margin)
+				changed = changed || dur != test.DelayOut
+			}
+
+			assert.True(t, changed)
+		})
+	}
+}
+
+// TestCyclicDelay tests if the cyclic delay function increases the delay up to
+// the limit, then starts over from the base
+func TestCyclicDelay(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Count    int
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "First call",
+			Count:    0,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Third call",
+			Count:    2,
+			DelayOut: time.Second * 4,
+		},
+		{
+			Name:     "Call at limit",
+			Count:    4,
+			DelayOut: time.Second * 10,
+		},
+		{
+			Name:     "First call of next cycle",
+			Count:    5,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Nth call",
+			Count:    28,
+			DelayOut: time.Second * 8,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			fn := CyclicDelay(time.Second, time.Second*10)
+			dur := fn(test.Count)
+
+			assert.Equal(t, test.DelayOut, dur)
+		})
+	}
+}
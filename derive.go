@@ -0,0 +1,66 @@
+package retrier
+
+import "time"
+
+// WithMax returns a copy of the retrier with a different number of max
+// retries. The original retrier is not modified. The copy tracks its own runs
+// in flight, so draining one does not drain the other.
+func (r *Retrier) WithMax(max int) *Retrier {
+	c := r.clone()
+	c.max = max
+	return c
+}
+
+// WithDelay returns a copy of the retrier with a different delay function,
+// which replaces the backoff if the retrier was created with one. The original
+// retrier is not modified. A nil delay function is replaced with NoDelay, the
+// same way as in NewRetrier.
+func (r *Retrier) WithDelay(delayf DelayFunc) *Retrier {
+	if delayf == nil {
+		delayf = NoDelay()
+	}
+	c := r.clone()
+	c.delayf = delayf
+	c.backoff = nil
+	return c
+}
+
+// WithOptions returns a copy of the retrier with additional configuration
+// applied on top of the existing configuration. The original retrier is not
+// modified.
+func (r *Retrier) WithOptions(opts ...Option) *Retrier {
+	c := r.clone()
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Max returns the upper limit of retries of the retrier, which is NoLimit if
+// the retrier has no limit.
+func (r *Retrier) Max() int {
+	return r.max
+}
+
+// Delay returns the delay function of the retrier. For a retrier created with
+// a backoff, the function takes the next delay from the backoff.
+func (r *Retrier) Delay() DelayFunc {
+	return r.delayf
+}
+
+// Name returns the name of the retrier, or an empty string if it has no name.
+func (r *Retrier) Name() string {
+	return r.name
+}
+
+// NextDelay returns the delay before the next attempt after a number of
+// retries, when the last attempt failed with an error. Delays requested by the
+// error, cooldowns, jitter and rounding are applied the same way as in runs.
+func (r *Retrier) NextDelay(retries int, err error) time.Duration {
+	return r.delay(retries, err)
+}
+
+// Budget returns the retry budget of the retrier, or nil if it has none.
+func (r *Retrier) Budget() *Budget {
+	return r.budget
+}
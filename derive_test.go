@@ -0,0 +1,136 @@
+package retrier
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDerive tests if derived retriers use the changed configuration while the
+// original retrier keeps its own
+func TestDerive(t *testing.T) {
+	base := NewRetrier(
+		1,
+		ConstantDelay(time.Millisecond),
+		WithCooldownFor(func(error) bool { return false }, time.Minute),
+	)
+
+	tests := []struct {
+		Name     string
+		Retrier  *Retrier
+		Attempts int
+		Delay    time.Duration
+	}{
+		{
+			Name:     "Base retrier",
+			Retrier:  base,
+			Attempts: 2,
+			Delay:    time.Millisecond,
+		},
+		{
+			Name:     "Derived max",
+			Retrier:  base.WithMax(3),
+			Attempts: 4,
+			Delay:    time.Millisecond,
+		},
+		{
+			Name:     "Derived delay",
+			Retrier:  base.WithDelay(ConstantDelay(time.Millisecond * 2)),
+			Attempts: 2,
+			Delay:    time.Millisecond * 2,
+		},
+		{
+			Name:     "Derived nil delay",
+			Retrier:  base.WithDelay(nil),
+			Attempts: 2,
+			Delay:    0,
+		},
+		{
+			Name: "Derived options",
+			Retrier: base.WithOptions(
+				WithCooldownFor(func(error) bool { return true }, time.Millisecond*3),
+			),
+			Attempts: 2,
+			Delay:    time.Millisecond * 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			delays := []time.Duration{}
+			retr := test.Retrier.WithOptions(
+				WithOnRetry(func(_ int, _ error, delay time.Duration) {
+					delays = append(delays, delay)
+				}),
+			)
+
+			res, _ := retr.RunResult(func() (error, bool) {
+				return fmt.Errorf("error"), true
+			})
+
+			assert.Equal(t, test.Attempts, res.Attempts)
+			assert.Equal(t, test.Attempts-1, len(delays))
+			assert.Equal(t, test.Delay, delays[0])
+		})
+	}
+
+	assert.Equal(t, 1, base.max)
+	assert.Nil(t, base.onRetry)
+	assert.Len(t, base.cooldowns, 1)
+}
+
+// TestGetters tests if the configuration of a retrier can be inspected
+func TestGetters(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Retrier *Retrier
+		Max     int
+		Delay   time.Duration
+		Label   string
+	}{
+		{
+			Name:    "Limited retrier",
+			Retrier: NewRetrier(3, ConstantDelay(time.Second), WithName("users")),
+			Max:     3,
+			Delay:   time.Second,
+			Label:   "users",
+		},
+		{
+			Name:    "Unlimited retrier",
+			Retrier: NewForeverRetrier(LinearDelay(time.Second)),
+			Max:     NoLimit,
+			Delay:   time.Second * 3,
+			Label:   "",
+		},
+		{
+			Name:    "Derived retrier",
+			Retrier: NewRetrier(3, NoDelay()).WithMax(5),
+			Max:     5,
+			Delay:   0,
+			Label:   "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Max, test.Retrier.Max())
+			assert.Equal(t, test.Delay, test.Retrier.Delay()(2))
+			assert.Equal(t, test.Label, test.Retrier.Name())
+		})
+	}
+}
+
+// TestNextDelay tests if the delay before the next attempt applies the delay
+// requested by the error of the last attempt
+func TestNextDelay(t *testing.T) {
+	errTask := fmt.Errorf("task error")
+	budget := NewBudget(1, time.Second)
+	retr := NewRetrier(3, LinearDelay(time.Second), WithBudget(budget))
+
+	assert.Equal(t, time.Second*2, retr.NextDelay(1, errTask))
+	assert.Equal(t, time.Second*5, retr.NextDelay(1, RetryAfter(errTask, time.Second*5)))
+	assert.Same(t, budget, retr.Budget())
+	assert.Nil(t, NewRetrier(3, NoDelay()).Budget())
+}
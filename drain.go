@@ -0,0 +1,43 @@
+package retrier
+
+import "sync"
+
+// drainState tracks the runs of a retrier that are in flight, and whether the
+// retrier still accepts new runs.
+type drainState struct {
+	mu       sync.Mutex
+	draining bool
+	wg       sync.WaitGroup
+}
+
+// Drain stops the retrier from accepting new runs, which return
+// ErrShuttingDown immediately. Runs that are already in flight are not
+// affected and continue until they complete.
+func (r *Retrier) Drain() {
+	r.drain.mu.Lock()
+	defer r.drain.mu.Unlock()
+	r.drain.draining = true
+}
+
+// Wait blocks until all runs of the retrier that are in flight have completed.
+// It is meant to be called after Drain, so that no new runs start meanwhile.
+func (r *Retrier) Wait() {
+	r.drain.wg.Wait()
+}
+
+// enter registers a new run as in flight, or reports false if the retrier
+// does not accept new runs.
+func (r *Retrier) enter() bool {
+	r.drain.mu.Lock()
+	defer r.drain.mu.Unlock()
+	if r.drain.draining {
+		return false
+	}
+	r.drain.wg.Add(1)
+	return true
+}
+
+// leave marks a run as no longer in flight.
+func (r *Retrier) leave() {
+	r.drain.wg.Done()
+}
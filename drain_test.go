@@ -0,0 +1,57 @@
+package retrier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDrain tests if a drained retrier rejects new runs while the runs in
+// flight complete, and if waiting blocks until they have completed
+func TestDrain(t *testing.T) {
+	retr := NewRetrier(5, NoDelay())
+
+	started := make(chan bool)
+	done := make(chan error, 1)
+	go func() {
+		done <- retr.RunCtx(
+			context.TODO(),
+			func(ctx context.Context) (error, bool) {
+				close(started)
+				time.Sleep(time.Millisecond * 50)
+				return nil, false
+			},
+		)
+	}()
+	<-started
+
+	retr.Drain()
+
+	cnt := 0
+	err := retr.Run(func() (error, bool) {
+		cnt++
+		return nil, false
+	})
+	assert.ErrorIs(t, err, ErrShuttingDown)
+	assert.Equal(t, 0, cnt)
+
+	ch := make(chan bool)
+	go func() {
+		retr.Wait()
+		ch <- true
+	}()
+	select {
+	case <-time.After(time.Second):
+		panic("test function hang")
+	case <-ch:
+	}
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	default:
+		assert.Fail(t, "wait returned before the run in flight completed")
+	}
+}
@@ -0,0 +1,273 @@
+package retrier
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidConfig is returned when a retrier is created with an invalid
+// configuration.
+var ErrInvalidConfig = errors.New("invalid retrier configuration")
+
+// ErrMaxRetriesExceeded is the reason for stopping when the task has been
+// retried as many times as the retrier allows.
+var ErrMaxRetriesExceeded = errors.New("failed after max retries")
+
+// ErrMaxTotalSleep is the reason for stopping when the total time spent
+// sleeping between retries would exceed the configured limit.
+var ErrMaxTotalSleep = errors.New("failed after max total sleep")
+
+// ErrDeadlineTooShort is the reason for stopping when the next delay would
+// not end before the deadline of the context, so no further attempt could
+// complete in time.
+var ErrDeadlineTooShort = errors.New("failed before deadline")
+
+// ErrBudgetExhausted is the reason for stopping when the retry budget of the
+// retrier has no retries left.
+var ErrBudgetExhausted = errors.New("failed after retry budget exhausted")
+
+// ErrAborted is the reason for stopping when the abort channel of the retrier
+// has been closed.
+var ErrAborted = errors.New("failed after abort")
+
+// ErrUnhealthy is the reason for stopping when the health gate did not report
+// healthy within its max wait.
+var ErrUnhealthy = errors.New("failed after health gate timeout")
+
+// ErrShuttingDown is returned by runs that start after the retrier has been
+// drained.
+var ErrShuttingDown = errors.New("retrier is shutting down")
+
+// ErrMaxElapsedTime is the reason for stopping when the next attempt would
+// start after the elapsed time limit of the run.
+var ErrMaxElapsedTime = errors.New("failed after max elapsed time")
+
+// ExhaustedError is returned when the retrier stops retrying a task for some
+// reason other than the task deciding not to retry. It matches the reason with
+// errors.Is and unwraps to the last error of the task.
+type ExhaustedError struct {
+	// Reason is the sentinel error describing why the retrier stopped.
+	Reason error
+
+	// Attempts is the number of times the task was executed.
+	Attempts int
+
+	// Elapsed is the total time from the start of the run until it stopped.
+	Elapsed time.Duration
+
+	// LastErr is the error returned by the last attempt of the task, or the
+	// errors of every attempt joined together with error aggregation.
+	LastErr error
+
+	// Errors are the errors returned by every attempt of the task in order,
+	// ending with the last error. It is only populated when the retrier was
+	// configured with WithErrorHistory, and is nil otherwise.
+	Errors []error
+}
+
+// Error returns the reason for stopping followed by the last error of the task.
+func (e *ExhaustedError) Error() string {
+	if e.LastErr == nil {
+		return e.Reason.Error()
+	}
+	return e.Reason.Error() + ": " + e.LastErr.Error()
+}
+
+// Unwrap returns the last error of the task.
+func (e *ExhaustedError) Unwrap() error {
+	return e.LastErr
+}
+
+// Is reports whether the target is the reason for stopping.
+func (e *ExhaustedError) Is(target error) bool {
+	return target == e.Reason
+}
+
+// retryAfterError is an error of a task that requests a specific delay before
+// the next retry.
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+// RetryAfter wraps an error of a task with a request to wait a specific delay
+// before the next retry, such as the delay that a server asks for when it is
+// rate limiting or overloaded. The requested delay overrides the delay function
+// and cooldowns of the retrier, and it is not jittered. The wrapped error can
+// still be matched with errors.Is and errors.As.
+func RetryAfter(err error, delay time.Duration) error {
+	return &retryAfterError{
+		err:   err,
+		delay: delay,
+	}
+}
+
+// Error returns the message of the wrapped error.
+func (e *retryAfterError) Error() string {
+	if e.err == nil {
+		return "retry after " + e.delay.String()
+	}
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+// PanicError is the error of an attempt that panicked while the retrier was
+// recovering panics.
+type PanicError struct {
+	// Value is the value that the task panicked with.
+	Value any
+
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+// Error returns the value of the panic followed by the stack trace.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the value of the panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// joinError is the errors of every attempt of a task joined together, which
+// behaves like the errors returned by errors.Join.
+type joinError struct {
+	errs []error
+}
+
+// join joins errors into a single error, discarding nil errors. It returns
+// nil if every error is nil.
+func join(errs ...error) error {
+	e := &joinError{}
+	for _, err := range errs {
+		if err != nil {
+			e.errs = append(e.errs, err)
+		}
+	}
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error returns the messages of the errors separated by newlines.
+func (e *joinError) Error() string {
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the joined errors.
+func (e *joinError) Unwrap() []error {
+	return e.errs
+}
+
+// Is reports whether any of the joined errors matches the target, for
+// versions of Go where errors.Is does not unwrap multiple errors.
+func (e *joinError) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the joined errors that matches the target, for
+// versions of Go where errors.As does not unwrap multiple errors.
+func (e *joinError) As(target any) bool {
+	for _, err := range e.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// permanentError is an error of a task that should not be retried.
+type permanentError struct {
+	err error
+}
+
+// Permanent wraps an error of a task to stop retrying it, regardless of the
+// decision of the task or the classifier of the retrier. The wrapped error can
+// still be matched with errors.Is and errors.As. Wrapping a nil error returns
+// nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Error returns the message of the wrapped error.
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// transientError is an error of a task that should be retried.
+type transientError struct {
+	err error
+}
+
+// Transient wraps an error of a task to retry it, regardless of the decision
+// of the task or the classifier of the retrier. Permanent errors take
+// precedence over transient errors, and policies of the kind of operation are
+// still applied. The wrapped error can still be matched with errors.Is and
+// errors.As. Wrapping a nil error returns nil.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err}
+}
+
+// Error returns the message of the wrapped error.
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+// classify decides whether a task is retried after an attempt from the error
+// and the decision of the task. Permanent and transient errors take precedence
+// over the classifier of the retrier, which takes precedence over the task.
+// Recovered panics are decided by the configuration of the retrier.
+func (r *Retrier) classify(err error, retry bool) bool {
+	if err == nil {
+		return retry
+	}
+
+	var perm *permanentError
+	var tran *transientError
+	var pnc *PanicError
+	if errors.As(err, &perm) {
+		return false
+	} else if errors.As(err, &tran) {
+		return true
+	} else if errors.As(err, &pnc) {
+		return retry
+	} else if r.retryIf != nil {
+		return r.retryIf(err)
+	}
+	return retry
+}
@@ -0,0 +1,210 @@
+package retrier
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRetryAfter tests if a task can request a specific delay before the next
+// retry that overrides the delay function, while the wrapped error is still
+// returned by the run
+func TestRetryAfter(t *testing.T) {
+	errRateLimit := fmt.Errorf("rate limited")
+
+	tests := []struct {
+		Name    string
+		Error   error
+		Delay   time.Duration
+		Message string
+	}{
+		{
+			Name:    "Requested delay overrides delay function",
+			Error:   RetryAfter(errRateLimit, time.Millisecond*30),
+			Delay:   time.Millisecond * 30,
+			Message: "failed after max retries: rate limited",
+		},
+		{
+			Name:    "Requested delay without error",
+			Error:   RetryAfter(nil, time.Millisecond*30),
+			Delay:   time.Millisecond * 30,
+			Message: "failed after max retries: retry after 30ms",
+		},
+		{
+			Name:    "Delay function without request",
+			Error:   errRateLimit,
+			Delay:   time.Millisecond * 5,
+			Message: "failed after max retries: rate limited",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(1, ConstantDelay(time.Millisecond*5))
+
+			var err error
+			ch := make(chan bool)
+			st := time.Now()
+			go func() {
+				err = retr.Run(func() (error, bool) {
+					return test.Error, true
+				})
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+			dif := time.Since(st)
+
+			assert.EqualError(t, err, test.Message)
+			if errors.Unwrap(test.Error) != nil {
+				assert.ErrorIs(t, err, errRateLimit)
+			}
+
+			assert.GreaterOrEqual(t, dif, test.Delay)
+			assert.Less(t, dif, test.Delay+time.Millisecond*20)
+		})
+	}
+}
+
+// TestPermanentTransient tests if permanent and transient errors decide
+// whether a task is retried, taking precedence over the classifier
+func TestPermanentTransient(t *testing.T) {
+	errTask := fmt.Errorf("task error")
+
+	tests := []struct {
+		Name     string
+		Error    error
+		RetryIf  func(error) bool
+		Attempts int
+		Message  string
+	}{
+		{
+			Name:     "Plain error is retried",
+			Error:    errTask,
+			RetryIf:  nil,
+			Attempts: 3,
+			Message:  "failed after max retries: task error",
+		},
+		{
+			Name:     "Permanent error is not retried",
+			Error:    Permanent(errTask),
+			RetryIf:  nil,
+			Attempts: 1,
+			Message:  "task error",
+		},
+		{
+			Name:  "Permanent error overrides classifier",
+			Error: Permanent(errTask),
+			RetryIf: func(error) bool {
+				return true
+			},
+			Attempts: 1,
+			Message:  "task error",
+		},
+		{
+			Name:  "Transient error overrides classifier",
+			Error: Transient(errTask),
+			RetryIf: func(error) bool {
+				return false
+			},
+			Attempts: 3,
+			Message:  "failed after max retries: task error",
+		},
+		{
+			Name:  "Permanent error overrides transient error",
+			Error: Permanent(Transient(errTask)),
+			RetryIf: func(error) bool {
+				return true
+			},
+			Attempts: 1,
+			Message:  "task error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, NoDelay(), WithRetryIf(test.RetryIf))
+
+			attempts := 0
+			err := retr.RunErr(func() error {
+				attempts++
+				return test.Error
+			})
+
+			assert.EqualError(t, err, test.Message)
+			assert.ErrorIs(t, err, errTask)
+			assert.Equal(t, test.Attempts, attempts)
+		})
+	}
+}
+
+// TestRunErrSuccess tests if an error-only task that returns no error
+// succeeds without being retried
+func TestRunErrSuccess(t *testing.T) {
+	retr := NewRetrier(2, NoDelay())
+
+	attempts := 0
+	err := retr.RunErr(func() error {
+		attempts++
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+// TestErrMaxRetriesExceeded tests if running out of retries can be matched
+// with the sentinel error while the last error of the task is still wrapped
+func TestErrMaxRetriesExceeded(t *testing.T) {
+	errTask := fmt.Errorf("task error")
+
+	tests := []struct {
+		Name      string
+		Retry     bool
+		Exhausted bool
+		Attempts  int
+	}{
+		{
+			Name:      "Retries run out",
+			Retry:     true,
+			Exhausted: true,
+			Attempts:  3,
+		},
+		{
+			Name:      "Task stops retrying",
+			Retry:     false,
+			Exhausted: false,
+			Attempts:  1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, NoDelay())
+
+			err := retr.Run(func() (error, bool) {
+				return errTask, test.Retry
+			})
+
+			assert.ErrorIs(t, err, errTask)
+			var exh *ExhaustedError
+			if test.Exhausted {
+				assert.ErrorIs(t, err, ErrMaxRetriesExceeded)
+				if assert.ErrorAs(t, err, &exh) {
+					assert.Equal(t, test.Attempts, exh.Attempts)
+					assert.Equal(t, errTask, exh.LastErr)
+					assert.Nil(t, exh.Errors)
+				}
+			} else {
+				assert.NotErrorIs(t, err, ErrMaxRetriesExceeded)
+				assert.False(t, errors.As(err, &exh))
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package retrier
+
+import (
+	"context"
+	"time"
+)
+
+// Event is an event in the lifecycle of a run, which is one of
+// AttemptStarted, AttemptFailed, Sleeping, Succeeded and Exhausted.
+type Event interface {
+	// event restricts the implementations to the events of this package.
+	event()
+}
+
+// AttemptStarted is published before an attempt of a task.
+type AttemptStarted struct {
+	// Name is the name of the retrier.
+	Name string
+
+	// Attempt is the number of the attempt, starting from 1.
+	Attempt int
+
+	// Delay is the delay that was slept before the attempt.
+	Delay time.Duration
+}
+
+// AttemptFailed is published after an attempt of a task that failed with an
+// error, or that requested to be retried.
+type AttemptFailed struct {
+	// Name is the name of the retrier.
+	Name string
+
+	// Attempt is the number of the attempt, starting from 1.
+	Attempt int
+
+	// Duration is how long the attempt took.
+	Duration time.Duration
+
+	// Err is the error of the attempt.
+	Err error
+
+	// Retry is whether the attempt was classified as retryable.
+	Retry bool
+}
+
+// Sleeping is published before sleeping to retry a task.
+type Sleeping struct {
+	// Name is the name of the retrier.
+	Name string
+
+	// Attempt is the number of the attempt that failed.
+	Attempt int
+
+	// Err is the error of the attempt that failed.
+	Err error
+
+	// Delay is the delay before the next attempt.
+	Delay time.Duration
+}
+
+// Succeeded is published when a run ends with the task succeeding.
+type Succeeded struct {
+	// Name is the name of the retrier.
+	Name string
+
+	// Result is the result of the run.
+	Result Result
+}
+
+// Exhausted is published when a run ends without the task succeeding.
+type Exhausted struct {
+	// Name is the name of the retrier.
+	Name string
+
+	// Reason is why the run ended, classified the same way as for the give up
+	// hook. Runs that end with the error of their own canceled or expired
+	// context are reported as canceled.
+	Reason GiveUpReason
+
+	// Result is the result of the run.
+	Result Result
+
+	// Err is the error of the run.
+	Err error
+}
+
+func (AttemptStarted) event() {}
+func (AttemptFailed) event()  {}
+func (Sleeping) event()       {}
+func (Succeeded) event()      {}
+func (Exhausted) event()      {}
+
+// Subscriber receives the events of retriers. Events are published
+// synchronously by the goroutine of the run, so Publish should return
+// quickly. Subscribers of retriers that run tasks concurrently must be safe
+// for concurrent use.
+type Subscriber interface {
+	Publish(e Event)
+}
+
+// SubscriberFunc adapts a function to the Subscriber interface.
+type SubscriberFunc func(e Event)
+
+// Publish calls the function with the event.
+func (f SubscriberFunc) Publish(e Event) {
+	f(e)
+}
+
+// EventChannel returns a subscriber that sends events to a channel. Sending
+// blocks the run until the event is received, so the channel should be
+// buffered or drained concurrently.
+func EventChannel(ch chan<- Event) Subscriber {
+	return SubscriberFunc(func(e Event) {
+		ch <- e
+	})
+}
+
+// WithEvents makes the retrier publish the events in the lifecycle of its
+// runs to a subscriber. The option can be used multiple times to add more
+// subscribers.
+func WithEvents(s Subscriber) Option {
+	return func(r *Retrier) {
+		r.interceptors = append(r.interceptors, func(
+			ctx context.Context,
+			next func(ctx context.Context) (error, bool),
+		) (error, bool) {
+			att, _ := AttemptFromContext(ctx)
+			name, _ := NameFromContext(ctx)
+			s.Publish(AttemptStarted{
+				Name:    name,
+				Attempt: att.Number,
+				Delay:   att.Delay,
+			})
+			return next(ctx)
+		})
+		r.observers = append(r.observers, eventObserver{s})
+	}
+}
+
+// eventObserver is an observer that publishes events to a subscriber.
+type eventObserver struct {
+	sub Subscriber
+}
+
+// ObserveAttempt publishes an AttemptFailed event if the attempt failed.
+func (o eventObserver) ObserveAttempt(name string, rec AttemptRecord) {
+	if rec.Err != nil || rec.Retry {
+		o.sub.Publish(AttemptFailed{
+			Name:     name,
+			Attempt:  rec.Attempt,
+			Duration: rec.Duration,
+			Err:      rec.Err,
+			Retry:    rec.Retry,
+		})
+	}
+}
+
+// ObserveRetry publishes a Sleeping event.
+func (o eventObserver) ObserveRetry(
+	name string,
+	attempt int,
+	err error,
+	delay time.Duration,
+) {
+	o.sub.Publish(Sleeping{
+		Name:    name,
+		Attempt: attempt,
+		Err:     err,
+		Delay:   delay,
+	})
+}
+
+// ObserveRun publishes a Succeeded or an Exhausted event. Runs are published
+// by observeRun with the reason of the retrier, so this is only called
+// directly, without the context of the run.
+func (o eventObserver) ObserveRun(name string, res Result, err error) {
+	o.observeRun(name, giveUpReason(context.Background(), err), res, err)
+}
+
+// observeRun publishes a Succeeded or an Exhausted event with the reason why
+// the run failed.
+func (o eventObserver) observeRun(
+	name string,
+	reason GiveUpReason,
+	res Result,
+	err error,
+) {
+	if err == nil {
+		o.sub.Publish(Succeeded{Name: name, Result: res})
+		return
+	}
+	o.sub.Publish(Exhausted{
+		Name:   name,
+		Reason: reason,
+		Result: res,
+		Err:    err,
+	})
+}
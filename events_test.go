@@ -0,0 +1,169 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEvents tests if the events in the lifecycle of runs are published in
+// order, with the reason why runs end without the task succeeding classified
+// the same way as for the give up hook
+func TestEvents(t *testing.T) {
+	failure := fmt.Errorf("error")
+
+	tests := []struct {
+		Name   string
+		Errors []error
+		Retry  bool
+		Cancel bool
+		Events []Event
+	}{
+		{
+			Name:   "Success",
+			Errors: []error{failure, nil},
+			Retry:  true,
+			Events: []Event{
+				AttemptStarted{Name: "users", Attempt: 1},
+				AttemptFailed{Name: "users", Attempt: 1, Err: failure, Retry: true},
+				Sleeping{Name: "users", Attempt: 1, Err: failure, Delay: time.Millisecond},
+				AttemptStarted{Name: "users", Attempt: 2, Delay: time.Millisecond},
+				Succeeded{Name: "users", Result: Result{Attempts: 2}},
+			},
+		},
+		{
+			Name:   "Exhausted",
+			Errors: []error{failure, failure},
+			Retry:  true,
+			Events: []Event{
+				AttemptStarted{Name: "users", Attempt: 1},
+				AttemptFailed{Name: "users", Attempt: 1, Err: failure, Retry: true},
+				Sleeping{Name: "users", Attempt: 1, Err: failure, Delay: time.Millisecond},
+				AttemptStarted{Name: "users", Attempt: 2, Delay: time.Millisecond},
+				AttemptFailed{Name: "users", Attempt: 2, Err: failure, Retry: true},
+				Exhausted{
+					Name:   "users",
+					Reason: GiveUpExhausted,
+					Result: Result{Attempts: 2},
+					Err:    fmt.Errorf("failed after max retries: error"),
+				},
+			},
+		},
+		{
+			Name:   "Fatal",
+			Errors: []error{failure},
+			Retry:  false,
+			Events: []Event{
+				AttemptStarted{Name: "users", Attempt: 1},
+				AttemptFailed{Name: "users", Attempt: 1, Err: failure},
+				Exhausted{
+					Name:   "users",
+					Reason: GiveUpFatal,
+					Result: Result{Attempts: 1},
+					Err:    failure,
+				},
+			},
+		},
+		{
+			Name:   "Canceled",
+			Errors: []error{context.Canceled},
+			Retry:  false,
+			Cancel: true,
+			Events: []Event{
+				AttemptStarted{Name: "users", Attempt: 1},
+				AttemptFailed{Name: "users", Attempt: 1, Err: context.Canceled},
+				Exhausted{
+					Name:   "users",
+					Reason: GiveUpCanceled,
+					Result: Result{Attempts: 1},
+					Err:    context.Canceled,
+				},
+			},
+		},
+		{
+			Name:   "Context error of the task",
+			Errors: []error{context.DeadlineExceeded},
+			Retry:  false,
+			Events: []Event{
+				AttemptStarted{Name: "users", Attempt: 1},
+				AttemptFailed{Name: "users", Attempt: 1, Err: context.DeadlineExceeded},
+				Exhausted{
+					Name:   "users",
+					Reason: GiveUpFatal,
+					Result: Result{Attempts: 1},
+					Err:    fmt.Errorf("context deadline exceeded"),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			events := []Event{}
+			reason := GiveUpReason(-1)
+			retr := NewRetrier(1, ConstantDelay(time.Millisecond),
+				WithName("users"),
+				WithOnGiveUp(func(r GiveUpReason, res Result, err error) {
+					reason = r
+				}),
+				WithEvents(SubscriberFunc(func(e Event) {
+					// Durations vary, so they are left out of the events, and
+					// errors of runs are compared by their messages.
+					switch ev := e.(type) {
+					case AttemptFailed:
+						ev.Duration = 0
+						e = ev
+					case Succeeded:
+						ev.Result.Elapsed = 0
+						e = ev
+					case Exhausted:
+						ev.Result.Elapsed = 0
+						ev.Err = fmt.Errorf("%v", ev.Err)
+						e = ev
+					}
+					events = append(events, e)
+				})),
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			count := 0
+			retr.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+				count++
+				if test.Cancel {
+					cancel()
+				}
+				err := test.Errors[count-1]
+				return err, err != nil && test.Retry
+			})
+
+			assert.Equal(t, test.Events, events)
+			if ex, ok := events[len(events)-1].(Exhausted); ok {
+				assert.Equal(t, ex.Reason, reason)
+			}
+		})
+	}
+}
+
+// TestEventChannel tests if events are sent to a channel
+func TestEventChannel(t *testing.T) {
+	ch := make(chan Event, 10)
+	retr := NewRetrier(1, NoDelay(), WithEvents(EventChannel(ch)))
+
+	retr.Run(func() (error, bool) {
+		return nil, false
+	})
+	close(ch)
+
+	events := []Event{}
+	for e := range ch {
+		events = append(events, e)
+	}
+	assert.Len(t, events, 2)
+	assert.IsType(t, AttemptStarted{}, events[0])
+	assert.IsType(t, Succeeded{}, events[1])
+}
@@ -0,0 +1,76 @@
+package retrier
+
+import (
+	"context"
+	"sync"
+)
+
+// CancelPolicy defines which task failures in a group cancel the other tasks.
+type CancelPolicy int
+
+const (
+	// CancelOnFatal cancels the group only when a task fails with an error
+	// that it decided not to retry. Tasks that run out of retries record
+	// their error but let the other tasks continue.
+	CancelOnFatal CancelPolicy = iota
+
+	// CancelOnAny cancels the group when a task fails for any reason,
+	// including running out of retries.
+	CancelOnAny
+)
+
+// Group runs multiple related tasks concurrently with the policy of a retrier.
+// All tasks are expected to succeed, so the group cancels the remaining tasks
+// as soon as one of them fails in a way that the cancel policy considers final.
+type Group struct {
+	retr   *Retrier
+	policy CancelPolicy
+	cncl   context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	ctx    context.Context
+	err    error
+}
+
+// NewGroup creates a group from a parent context, a retrier and a cancel
+// policy. The returned context is derived from the parent context, and it is
+// canceled when a task fails according to the policy, or when Wait returns.
+func NewGroup(
+	ctx context.Context,
+	retr *Retrier,
+	policy CancelPolicy,
+) (*Group, context.Context) {
+	ctx, cncl := context.WithCancel(ctx)
+	return &Group{
+		retr:   retr,
+		policy: policy,
+		cncl:   cncl,
+		ctx:    ctx,
+	}, ctx
+}
+
+// Go runs a work task in a new goroutine with the retrier of the group.
+func (g *Group) Go(work func(ctx context.Context) (error, bool)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		err, exhausted := g.retr.runTracked(g.ctx, work)
+		if err != nil {
+			g.once.Do(func() {
+				g.err = err
+			})
+			if !exhausted || g.policy == CancelOnAny {
+				g.cncl()
+			}
+		}
+	}()
+}
+
+// Wait blocks until all tasks of the group have finished, then returns the
+// first error returned by any of the tasks.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cncl()
+	return g.err
+}
@@ -0,0 +1,97 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGroup tests if a group of tasks waits for all of its tasks and cancels
+// the remaining tasks when one of them fails according to the cancel policy
+func TestGroup(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Policy   CancelPolicy
+		Failing  func(ctx context.Context) (error, bool)
+		Canceled bool
+		Error    error
+	}{
+		{
+			Name:   "Fatal failure cancels siblings",
+			Policy: CancelOnFatal,
+			Failing: func(ctx context.Context) (error, bool) {
+				return fmt.Errorf("fatal error"), false
+			},
+			Canceled: true,
+			Error:    fmt.Errorf("fatal error"),
+		},
+		{
+			Name:   "Exhausted failure does not cancel siblings",
+			Policy: CancelOnFatal,
+			Failing: func(ctx context.Context) (error, bool) {
+				return fmt.Errorf("error"), true
+			},
+			Canceled: false,
+			Error:    fmt.Errorf("failed after max retries: error"),
+		},
+		{
+			Name:   "Exhausted failure cancels siblings",
+			Policy: CancelOnAny,
+			Failing: func(ctx context.Context) (error, bool) {
+				return fmt.Errorf("error"), true
+			},
+			Canceled: true,
+			Error:    fmt.Errorf("failed after max retries: error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, ConstantDelay(time.Millisecond))
+			grp, ctx := NewGroup(context.TODO(), retr, test.Policy)
+
+			sibling := make(chan error, 1)
+			grp.Go(test.Failing)
+			grp.Go(func(ctx context.Context) (error, bool) {
+				select {
+				case <-ctx.Done():
+					sibling <- ctx.Err()
+					return ctx.Err(), false
+				case <-time.After(time.Millisecond * 50):
+					sibling <- nil
+					return nil, false
+				}
+			})
+
+			var err error
+			ch := make(chan bool)
+			go func() {
+				err = grp.Wait()
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			if test.Canceled {
+				// A sibling that starts after the group is canceled is not
+				// attempted at all, so it may not report anything.
+				select {
+				case err := <-sibling:
+					assert.ErrorIs(t, err, context.Canceled)
+				default:
+				}
+			} else {
+				assert.NoError(t, <-sibling)
+			}
+
+			assert.EqualError(t, err, test.Error.Error())
+			assert.Error(t, ctx.Err())
+		})
+	}
+}
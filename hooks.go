@@ -0,0 +1,100 @@
+package retrier
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// GiveUpReason describes why a run failed without the task succeeding.
+type GiveUpReason int
+
+const (
+	// GiveUpExhausted means the retrier stopped retrying a task that still
+	// requested to be retried, such as after running out of retries.
+	GiveUpExhausted GiveUpReason = iota
+
+	// GiveUpFatal means the task failed with an error that was not retried.
+	GiveUpFatal
+
+	// GiveUpCanceled means the context of the run was canceled or its
+	// deadline was exceeded.
+	GiveUpCanceled
+)
+
+// String returns the name of the reason.
+func (r GiveUpReason) String() string {
+	switch r {
+	case GiveUpExhausted:
+		return "exhausted"
+	case GiveUpFatal:
+		return "fatal"
+	case GiveUpCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// giveUpReason classifies why a run failed from the context of the run and
+// its error. Runs that end with the error of their own canceled or expired
+// context are canceled, while other context errors of the task are fatal.
+func giveUpReason(ctx context.Context, err error) GiveUpReason {
+	if _, ok := err.(*ExhaustedError); ok {
+		return GiveUpExhausted
+	} else if cerr := ctx.Err(); cerr != nil && errors.Is(err, cerr) {
+		return GiveUpCanceled
+	}
+	return GiveUpFatal
+}
+
+// notify calls the success or give up hook of the retrier with the result of
+// a run, its error and the reason why it failed.
+func (r *Retrier) notify(reason GiveUpReason, res Result, err error) {
+	if err == nil {
+		if r.onSuccess != nil {
+			r.onSuccess(res)
+		}
+		return
+	}
+	if r.onGiveUp != nil {
+		r.onGiveUp(reason, res, err)
+	}
+}
+
+// Observer is notified of the attempts and runs of a retrier, such as to
+// record metrics. Its methods are called synchronously by the goroutine of the
+// run, so they should return quickly. Observers of retriers that run tasks
+// concurrently must be safe for concurrent use.
+type Observer interface {
+	// ObserveAttempt is called after every attempt of a task with the name of
+	// the retrier and the record of the attempt.
+	ObserveAttempt(name string, rec AttemptRecord)
+
+	// ObserveRetry is called before sleeping to retry a task with the name of
+	// the retrier, the number of the attempt that failed, its error and the
+	// delay before the next attempt.
+	ObserveRetry(name string, attempt int, err error, delay time.Duration)
+
+	// ObserveRun is called when a run ends with the name of the retrier, the
+	// result of the run and its error. Runs that are rejected because the
+	// retrier is shutting down are not observed.
+	ObserveRun(name string, res Result, err error)
+}
+
+// reasonObserver is an observer that is also notified of the reason why a run
+// failed, which is classified once by the retrier for the give up hook and
+// every observer alike.
+type reasonObserver interface {
+	observeRun(name string, reason GiveUpReason, res Result, err error)
+}
+
+// Interceptor wraps an attempt of a task, such as to trace it. It receives the
+// context of the attempt, which carries the description of the attempt, and
+// must call next to execute the attempt unless it fails the attempt itself.
+// The error and the retry decision it returns are classified the same way as
+// those of the task.
+type Interceptor func(
+	ctx context.Context,
+	next func(ctx context.Context) (error, bool),
+) (error, bool)
@@ -0,0 +1,196 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLifecycleHooks tests if the success hook is called when a task succeeds
+// and the give up hook is called with the reason when it does not
+func TestLifecycleHooks(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Error    error
+		Retry    bool
+		Cancel   bool
+		Success  bool
+		Reason   GiveUpReason
+		Attempts int
+	}{
+		{
+			Name:     "Task succeeds",
+			Error:    nil,
+			Retry:    false,
+			Success:  true,
+			Attempts: 1,
+		},
+		{
+			Name:     "Retries run out",
+			Error:    fmt.Errorf("error"),
+			Retry:    true,
+			Success:  false,
+			Reason:   GiveUpExhausted,
+			Attempts: 3,
+		},
+		{
+			Name:     "Task fails fatally",
+			Error:    fmt.Errorf("error"),
+			Retry:    false,
+			Success:  false,
+			Reason:   GiveUpFatal,
+			Attempts: 1,
+		},
+		{
+			Name:     "Context is canceled",
+			Error:    fmt.Errorf("error"),
+			Retry:    true,
+			Cancel:   true,
+			Success:  false,
+			Reason:   GiveUpCanceled,
+			Attempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var success *Result
+			var giveUp *Result
+			var reason GiveUpReason
+			retr := NewRetrier(
+				2,
+				ConstantDelay(time.Millisecond),
+				WithOnSuccess(func(res Result) {
+					success = &res
+				}),
+				WithOnGiveUp(func(rsn GiveUpReason, res Result, err error) {
+					assert.Error(t, err)
+					giveUp = &res
+					reason = rsn
+				}),
+			)
+
+			ctx, cncl := context.WithCancel(context.TODO())
+			defer cncl()
+			retr.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+				if test.Cancel {
+					cncl()
+				}
+				return test.Error, test.Retry
+			})
+
+			if test.Success {
+				if assert.NotNil(t, success) {
+					assert.Equal(t, test.Attempts, success.Attempts)
+				}
+				assert.Nil(t, giveUp)
+			} else {
+				if assert.NotNil(t, giveUp) {
+					assert.Equal(t, test.Attempts, giveUp.Attempts)
+				}
+				assert.Equal(t, test.Reason, reason)
+				assert.Nil(t, success)
+			}
+		})
+	}
+}
+
+// recordingObserver is an observer that records the events it is notified of.
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) ObserveAttempt(name string, rec AttemptRecord) {
+	o.events = append(o.events, fmt.Sprintf("%s attempt %d %v", name, rec.Attempt, rec.Err))
+}
+
+func (o *recordingObserver) ObserveRetry(name string, attempt int, err error, delay time.Duration) {
+	o.events = append(o.events, fmt.Sprintf("%s retry %d %v", name, attempt, delay))
+}
+
+func (o *recordingObserver) ObserveRun(name string, res Result, err error) {
+	o.events = append(o.events, fmt.Sprintf("%s run %d %v", name, res.Attempts, err))
+}
+
+// TestObservers tests if every observer is notified of the attempts, retries
+// and the end of runs, including observers of derived retriers
+func TestObservers(t *testing.T) {
+	first := &recordingObserver{}
+	second := &recordingObserver{}
+	base := NewRetrier(1, ConstantDelay(time.Millisecond),
+		WithName("users"),
+		WithObserver(first),
+	)
+	retr := base.WithOptions(WithObserver(second))
+
+	err := retr.Run(func() (error, bool) {
+		return fmt.Errorf("error"), true
+	})
+
+	events := []string{
+		"users attempt 1 error",
+		"users retry 1 1ms",
+		"users attempt 2 error",
+		"users run 2 failed after max retries: error",
+	}
+	assert.Error(t, err)
+	assert.Equal(t, events, first.events)
+	assert.Equal(t, events, second.events)
+
+	first.events = nil
+	base.Run(func() (error, bool) {
+		return nil, false
+	})
+	assert.Equal(t, []string{"users attempt 1 <nil>", "users run 1 <nil>"}, first.events)
+	assert.Len(t, second.events, 4)
+}
+
+// TestInterceptors tests if interceptors wrap every attempt in the order they
+// were added, receive the description of the attempt, and can change the
+// outcome of the attempt
+func TestInterceptors(t *testing.T) {
+	events := []string{}
+	record := func(label string) Interceptor {
+		return func(
+			ctx context.Context,
+			next func(ctx context.Context) (error, bool),
+		) (error, bool) {
+			att, _ := AttemptFromContext(ctx)
+			events = append(events, fmt.Sprintf("%s %d %v", label, att.Number, att.Delay))
+			return next(ctx)
+		}
+	}
+	fail := func(
+		ctx context.Context,
+		next func(ctx context.Context) (error, bool),
+	) (error, bool) {
+		if att, _ := AttemptFromContext(ctx); att.Number == 1 {
+			return fmt.Errorf("intercepted"), true
+		}
+		return next(ctx)
+	}
+
+	retr := NewRetrier(2, ConstantDelay(time.Millisecond),
+		WithInterceptor(record("outer")),
+		WithInterceptor(record("inner")),
+		WithInterceptor(fail),
+	)
+
+	calls := 0
+	err := retr.Run(func() (error, bool) {
+		calls++
+		return nil, false
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []string{
+		"outer 1 0s",
+		"inner 1 0s",
+		"outer 2 1ms",
+		"inner 2 1ms",
+	}, events)
+}
@@ -0,0 +1,43 @@
+package retrier
+
+import "context"
+
+// OpKind is the kind of operation that a task performs, which allows the same
+// retrier to apply different retry policies to different kinds of operations.
+type OpKind int
+
+const (
+	// OpAny is an operation of unspecified kind. By default, the task alone
+	// decides whether it should be retried.
+	OpAny OpKind = iota
+
+	// OpRead is an operation that only reads data, which is always safe to
+	// repeat. By default, the task alone decides whether it should be retried.
+	OpRead
+
+	// OpWrite is an operation that modifies data, which is only safe to repeat
+	// if it is idempotent. By default, it is never retried.
+	OpWrite
+)
+
+// allowRetry reports whether a task of some kind may be retried after failing
+// with an error. The policy of the kind can only prevent retrying a task that
+// requested to be retried; it can not force a retry.
+func (r *Retrier) allowRetry(kind OpKind, err error) bool {
+	if policy, ok := r.kindPolicies[kind]; ok {
+		return policy(err)
+	}
+	return kind != OpWrite
+}
+
+// RunCtxKind executes a work task the same way as RunCtx, with the retry
+// policy of the kind of operation that the task performs applied on top of the
+// decision of the task.
+func (r *Retrier) RunCtxKind(
+	ctx context.Context,
+	kind OpKind,
+	work func(ctx context.Context) (error, bool),
+) error {
+	_, err := r.run(ctx, kind, work)
+	return err
+}
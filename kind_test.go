@@ -0,0 +1,84 @@
+package retrier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRunCtxKind tests if tasks are retried according to the policy of the
+// kind of operation that they perform
+func TestRunCtxKind(t *testing.T) {
+	errConflict := fmt.Errorf("conflict")
+
+	tests := []struct {
+		Name     string
+		Kind     OpKind
+		Opts     []Option
+		Error    error
+		Attempts int
+	}{
+		{
+			Name:     "Read error is retried",
+			Kind:     OpRead,
+			Error:    fmt.Errorf("error"),
+			Attempts: 3,
+		},
+		{
+			Name:     "Write error stops immediately",
+			Kind:     OpWrite,
+			Error:    fmt.Errorf("error"),
+			Attempts: 1,
+		},
+		{
+			Name:     "Unspecified error is retried",
+			Kind:     OpAny,
+			Error:    fmt.Errorf("error"),
+			Attempts: 3,
+		},
+		{
+			Name: "Write error allowed by policy is retried",
+			Kind: OpWrite,
+			Opts: []Option{
+				WithKindPolicy(OpWrite, func(err error) bool {
+					return errors.Is(err, errConflict)
+				}),
+			},
+			Error:    errConflict,
+			Attempts: 3,
+		},
+		{
+			Name: "Read error denied by policy stops immediately",
+			Kind: OpRead,
+			Opts: []Option{
+				WithKindPolicy(OpRead, func(err error) bool {
+					return errors.Is(err, errConflict)
+				}),
+			},
+			Error:    fmt.Errorf("error"),
+			Attempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, NoDelay(), test.Opts...)
+
+			cnt := 0
+			err := retr.RunCtxKind(
+				context.TODO(),
+				test.Kind,
+				func(ctx context.Context) (error, bool) {
+					cnt++
+					return test.Error, true
+				},
+			)
+
+			assert.ErrorIs(t, err, test.Error)
+			assert.Equal(t, test.Attempts, cnt)
+		})
+	}
+}
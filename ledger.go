@@ -0,0 +1,78 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// AttemptRecord describes the outcome of a single attempt of a task.
+type AttemptRecord struct {
+	// Attempt is the number of the attempt, starting from 1.
+	Attempt int
+
+	// Start is the time when the attempt started.
+	Start time.Time
+
+	// Duration is the time it took for the task to complete the attempt.
+	Duration time.Duration
+
+	// Err is the error returned by the task.
+	Err error
+
+	// Retry is whether the task requested to be retried.
+	Retry bool
+
+	// Metadata is the metadata of the attempt, if the retrier was configured
+	// with a metadata function.
+	Metadata map[string]any
+}
+
+// LedgerPolicy defines what the retrier does when writing to the ledger fails.
+type LedgerPolicy int
+
+const (
+	// LedgerContinue logs the error of the ledger and continues running the
+	// task. The error is passed to the observers of the retrier that implement
+	// LedgerErrorObserver, such as the logger of retrierlog, or logged with
+	// the standard logger if there are none.
+	LedgerContinue LedgerPolicy = iota
+
+	// LedgerAbort stops running the task and returns the error of the ledger.
+	LedgerAbort
+)
+
+// LedgerErrorObserver is an observer that is also notified when writing to
+// the ledger fails and the retrier continues running the task, so that the
+// error is logged by the logger of the observer.
+type LedgerErrorObserver interface {
+	Observer
+
+	// ObserveLedgerError is called with the name of the retrier, the record
+	// that could not be written and the error of the ledger.
+	ObserveLedgerError(name string, rec AttemptRecord, err error)
+}
+
+// record writes the record of an attempt to the ledger of the retrier. The
+// error of the ledger is only returned if the policy is to abort.
+func (r *Retrier) record(ctx context.Context, rec AttemptRecord) error {
+	err := r.ledger(ctx, rec)
+	if err == nil {
+		return nil
+	} else if r.ledgerPolicy == LedgerAbort {
+		return fmt.Errorf("failed to write ledger: %w", err)
+	}
+
+	logged := false
+	for _, o := range r.observers {
+		if lo, ok := o.(LedgerErrorObserver); ok {
+			lo.ObserveLedgerError(r.name, rec, err)
+			logged = true
+		}
+	}
+	if !logged {
+		log.Printf("retrier: failed to write ledger: %v", err)
+	}
+	return nil
+}
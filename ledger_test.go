@@ -0,0 +1,200 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWithLedger tests if the ledger receives one record per attempt, and if
+// a failing ledger stops the run only when the policy is to abort
+func TestWithLedger(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Policy    LedgerPolicy
+		LedgerErr error
+		Records   int
+		Error     error
+	}{
+		{
+			Name:      "Ledger receives every attempt",
+			Policy:    LedgerContinue,
+			LedgerErr: nil,
+			Records:   3,
+			Error:     nil,
+		},
+		{
+			Name:      "Failing ledger is ignored",
+			Policy:    LedgerContinue,
+			LedgerErr: fmt.Errorf("database error"),
+			Records:   3,
+			Error:     nil,
+		},
+		{
+			Name:      "Failing ledger aborts the run",
+			Policy:    LedgerAbort,
+			LedgerErr: fmt.Errorf("database error"),
+			Records:   1,
+			Error:     fmt.Errorf("failed to write ledger: database error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			recs := []AttemptRecord{}
+			retr := NewRetrier(
+				5,
+				NoDelay(),
+				WithLedger(
+					func(ctx context.Context, rec AttemptRecord) error {
+						recs = append(recs, rec)
+						return test.LedgerErr
+					},
+					test.Policy,
+				),
+			)
+
+			cnt := 0
+			err := retr.Run(func() (error, bool) {
+				if cnt++; cnt < 3 {
+					return fmt.Errorf("error"), true
+				}
+				return nil, false
+			})
+
+			if test.Error != nil {
+				assert.EqualError(t, err, test.Error.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if assert.Len(t, recs, test.Records) {
+				for i, rec := range recs {
+					assert.Equal(t, i+1, rec.Attempt)
+					assert.False(t, rec.Start.IsZero())
+					assert.GreaterOrEqual(t, rec.Duration, time.Duration(0))
+					if i < 2 {
+						assert.EqualError(t, rec.Err, "error")
+						assert.True(t, rec.Retry)
+					} else {
+						assert.NoError(t, rec.Err)
+						assert.False(t, rec.Retry)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestWithAttemptMetadata tests if the records received by the ledger and the
+// context of the task carry the metadata of their attempt
+func TestWithAttemptMetadata(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Attempts int
+	}{
+		{
+			Name:     "Single attempt",
+			Attempts: 1,
+		},
+		{
+			Name:     "Multiple attempts",
+			Attempts: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx := context.WithValue(context.TODO(), "span", "abc")
+			recs := []AttemptRecord{}
+			retr := NewRetrier(
+				5,
+				NoDelay(),
+				WithLedger(
+					func(ctx context.Context, rec AttemptRecord) error {
+						recs = append(recs, rec)
+						return nil
+					},
+					LedgerContinue,
+				),
+				WithAttemptMetadata(
+					func(ctx context.Context, attempt int) map[string]any {
+						return map[string]any{
+							"span":    ctx.Value("span"),
+							"attempt": attempt,
+						}
+					},
+				),
+			)
+
+			cnt := 0
+			metas := []map[string]any{}
+			retr.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+				cnt++
+				meta, ok := MetadataFromContext(ctx)
+				assert.True(t, ok)
+				metas = append(metas, meta)
+				return nil, cnt < test.Attempts
+			})
+
+			if assert.Len(t, recs, test.Attempts) {
+				for i, rec := range recs {
+					assert.Equal(t, map[string]any{
+						"span":    "abc",
+						"attempt": i + 1,
+					}, rec.Metadata)
+					assert.Equal(t, rec.Metadata, metas[i])
+				}
+			}
+		})
+	}
+}
+
+// ledgerObserver is an observer that records the errors of the ledger.
+type ledgerObserver struct {
+	recordingObserver
+}
+
+func (o *ledgerObserver) ObserveLedgerError(name string, rec AttemptRecord, err error) {
+	o.events = append(o.events, fmt.Sprintf("%s ledger %d %v", name, rec.Attempt, err))
+}
+
+// TestLedgerErrorObserver tests if errors of the ledger that the retrier
+// continues after are passed to the observers that implement
+// LedgerErrorObserver
+func TestLedgerErrorObserver(t *testing.T) {
+	obs := &ledgerObserver{}
+	retr := NewRetrier(
+		5,
+		NoDelay(),
+		WithName("users"),
+		WithObserver(obs),
+		WithLedger(
+			func(ctx context.Context, rec AttemptRecord) error {
+				return fmt.Errorf("database error")
+			},
+			LedgerContinue,
+		),
+	)
+
+	cnt := 0
+	err := retr.Run(func() (error, bool) {
+		if cnt++; cnt < 2 {
+			return fmt.Errorf("error"), true
+		}
+		return nil, false
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"users attempt 1 error",
+		"users ledger 1 database error",
+		"users retry 1 0s",
+		"users attempt 2 <nil>",
+		"users ledger 2 database error",
+		"users run 2 <nil>",
+	}, obs.events)
+}
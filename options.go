@@ -0,0 +1,347 @@
+package retrier
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Option configures optional behavior of a retrier.
+type Option func(*Retrier)
+
+// WithMaxAttempts sets the limit of retries by the total number of attempts
+// of a task, including the first one, instead of the number of retries. A
+// value of 1 runs the task once without retries, and values below 1 disable
+// the limit.
+func WithMaxAttempts(n int) Option {
+	return func(r *Retrier) {
+		if n < 1 {
+			r.max = NoLimit
+		} else {
+			r.max = n - 1
+		}
+	}
+}
+
+// WithDelayRounding rounds each delay to the nearest multiple of some duration
+// before sleeping, which makes delays easier to read in logs and dashboards.
+// Rounding is applied after all other computation, so any jitter added by the
+// delay function is rounded as well. Halfway values are rounded away from zero.
+func WithDelayRounding(d time.Duration) Option {
+	return func(r *Retrier) {
+		r.rounding = d
+	}
+}
+
+// DefaultHealthPoll is the interval of polling the health gate when the
+// configured interval is not positive.
+const DefaultHealthPoll = time.Second
+
+// DefaultHealthMaxWait is the longest wait for the health gate to open when
+// the configured wait is not positive.
+const DefaultHealthMaxWait = time.Minute
+
+// WithHealthGate makes the retrier wait for a dependency to become healthy
+// before retrying a task. After the delay between attempts, the health function
+// is polled with some interval until it reports healthy. If it is still
+// unhealthy after the max wait, the retrier stops with ErrUnhealthy. This
+// avoids hammering a backend that is known to be down. Intervals and waits
+// that are not positive are replaced with DefaultHealthPoll and
+// DefaultHealthMaxWait.
+func WithHealthGate(
+	healthf func(ctx context.Context) bool,
+	poll time.Duration,
+	maxWait time.Duration,
+) Option {
+	if poll <= 0 {
+		poll = DefaultHealthPoll
+	}
+	if maxWait <= 0 {
+		maxWait = DefaultHealthMaxWait
+	}
+	return func(r *Retrier) {
+		r.healthf = healthf
+		r.poll = poll
+		r.healthWait = maxWait
+	}
+}
+
+// WithMaxElapsedTime limits the total elapsed time of a run, including the
+// time spent executing the task and sleeping between retries. The retrier
+// stops with ErrMaxElapsedTime when the next attempt would start after the
+// limit, so the last delay is never slept in vain.
+func WithMaxElapsedTime(d time.Duration) Option {
+	return func(r *Retrier) {
+		r.maxElapsed = d
+	}
+}
+
+// WithMaxTotalSleep limits the total time spent sleeping between retries. The
+// retrier stops with ErrMaxTotalSleep when the next delay would exceed the
+// limit. Unlike a limit on the elapsed time of a run, the time spent executing
+// the task does not count towards the limit, so it only bounds idle waiting.
+func WithMaxTotalSleep(d time.Duration) Option {
+	return func(r *Retrier) {
+		r.maxSleep = d
+	}
+}
+
+// WithLedger persists the record of each attempt of a task by calling the
+// ledger function after the attempt has completed. If writing to the ledger
+// fails, the policy decides whether the error is logged and the task keeps
+// running, or whether the run stops and returns the error of the ledger.
+func WithLedger(
+	ledger func(ctx context.Context, rec AttemptRecord) error,
+	policy LedgerPolicy,
+) Option {
+	return func(r *Retrier) {
+		r.ledger = ledger
+		r.ledgerPolicy = policy
+	}
+}
+
+// WithCooldownFor overrides the delay before the next retry with a cooldown
+// when the error of the task matches a predicate. This is useful for errors
+// like rate limits that warrant a much longer wait than the usual backoff.
+// The option can be used multiple times to add more rules, which are checked
+// in order and the first matching rule wins. A delay requested by the task
+// with RetryAfter takes precedence over all rules. Rounding still applies to
+// the cooldown.
+func WithCooldownFor(
+	pred func(err error) bool,
+	cooldown time.Duration,
+) Option {
+	return func(r *Retrier) {
+		r.cooldowns = append(r.cooldowns, cooldownRule{
+			pred: pred,
+			dur:  cooldown,
+		})
+	}
+}
+
+// WithJitterAboveThreshold randomly perturbs delays longer than a threshold by
+// up to some fraction of the delay in either direction, so that larger delays
+// do not synchronize between clients while small delays stay precise. Jitter
+// is applied after the delay function and cooldowns, so the threshold is
+// compared to their delay and jittered delays can exceed the cap of capped
+// delay functions by up to the fraction.
+func WithJitterAboveThreshold(
+	threshold time.Duration,
+	fraction float64,
+) Option {
+	return func(r *Retrier) {
+		r.jitterMin = threshold
+		r.jitterFrac = fraction
+	}
+}
+
+// WithAttemptMetadata attaches metadata such as span or request identifiers to
+// each attempt of a task. The metadata function is called once before each
+// attempt with the number of the attempt starting from 1, and the returned
+// metadata is attached to the context of the attempt, where the task and
+// interceptors read it with MetadataFromContext, and to the record of the
+// attempt that the ledger and observers receive. The metadata is not retained
+// by the retrier after the attempt ends.
+func WithAttemptMetadata(
+	metaf func(ctx context.Context, attempt int) map[string]any,
+) Option {
+	return func(r *Retrier) {
+		r.metaf = metaf
+	}
+}
+
+// WithRetryIf sets a classifier that decides whether a task is retried after
+// failing with an error, so that the same decision can be shared by many tasks.
+// The classifier replaces the decision of the task for errors, while permanent
+// and transient errors take precedence over it, and policies of the kind of
+// operation are still applied on top of it.
+func WithRetryIf(classify func(err error) bool) Option {
+	return func(r *Retrier) {
+		r.retryIf = classify
+	}
+}
+
+// WithOnRetry sets a hook that is called before sleeping to retry a task, with
+// the number of the attempt that failed, its error and the delay before the
+// next attempt. The hook is called synchronously, so it should return quickly.
+func WithOnRetry(hook func(attempt int, err error, nextDelay time.Duration)) Option {
+	return func(r *Retrier) {
+		r.onRetry = hook
+	}
+}
+
+// WithOnSuccess sets a hook that is called when a task succeeds, with the
+// result of the run.
+func WithOnSuccess(hook func(res Result)) Option {
+	return func(r *Retrier) {
+		r.onSuccess = hook
+	}
+}
+
+// WithOnGiveUp sets a hook that is called when a run ends without the task
+// succeeding, with the reason, the result of the run and the error. Runs that
+// are rejected because the retrier is shutting down do not call the hook.
+func WithOnGiveUp(hook func(reason GiveUpReason, res Result, err error)) Option {
+	return func(r *Retrier) {
+		r.onGiveUp = hook
+	}
+}
+
+// WithObserver adds an observer that is notified of the attempts and runs of
+// the retrier. Unlike hooks, the option can be used multiple times to add
+// more observers, which are notified in order.
+func WithObserver(o Observer) Option {
+	return func(r *Retrier) {
+		r.observers = append(r.observers, o)
+	}
+}
+
+// WithInterceptor adds an interceptor that wraps every attempt of a task. The
+// option can be used multiple times to add more interceptors, and the first
+// one added is the outermost.
+func WithInterceptor(i Interceptor) Option {
+	return func(r *Retrier) {
+		r.interceptors = append(r.interceptors, i)
+	}
+}
+
+// WithRecoverPanics makes the retrier recover panics of a task and convert them
+// to a *PanicError with the stack trace of the panic. Whether the attempt is
+// retried after a panic is decided by the retry flag.
+func WithRecoverPanics(retry bool) Option {
+	return func(r *Retrier) {
+		r.recoverPanics = true
+		r.retryPanics = retry
+	}
+}
+
+// WithErrorAggregation makes the retrier join the errors of every attempt of a
+// task when it gives up on the task, instead of only wrapping the last error.
+// The joined error separates the messages with newlines like errors.Join, and
+// each error can be matched with errors.Is and errors.As.
+func WithErrorAggregation(enabled bool) Option {
+	return func(r *Retrier) {
+		r.aggregate = enabled
+	}
+}
+
+// WithErrorHistory makes the retrier keep the errors of every attempt of a
+// task, and report them in the Errors field of the *ExhaustedError when it
+// gives up on the task. The errors are kept for the whole run, so retriers
+// without a limit of retries should not enable it.
+func WithErrorHistory(enabled bool) Option {
+	return func(r *Retrier) {
+		r.history = enabled
+	}
+}
+
+// WithKindPolicy sets the retry policy of a kind of operation, which decides
+// whether a task of that kind may be retried after failing with an error. The
+// policy is only consulted when the task requests to be retried, and it
+// replaces the default policy of the kind.
+func WithKindPolicy(kind OpKind, policy func(err error) bool) Option {
+	return func(r *Retrier) {
+		if r.kindPolicies == nil {
+			r.kindPolicies = map[OpKind]func(error) bool{}
+		}
+		r.kindPolicies[kind] = policy
+	}
+}
+
+// WithStartupJitter delays the first attempt of each run by a random duration
+// up to some offset, so that a fleet of clients starting at the same time do
+// not synchronize their attempts. Unlike jitter between retries, the offset is
+// only added once at the start of a run, and a new offset is drawn for every
+// run of a reused retrier.
+func WithStartupJitter(maxOffset time.Duration) Option {
+	return func(r *Retrier) {
+		r.startJitter = maxOffset
+	}
+}
+
+// WithBudget sets a retry budget that the retrier takes a token from before
+// every retry. When the budget has no retries left, the retrier stops with
+// ErrBudgetExhausted instead of retrying. The same budget can be shared by
+// many retriers.
+func WithBudget(b *Budget) Option {
+	return func(r *Retrier) {
+		r.budget = b
+	}
+}
+
+// WithName sets the name of the retrier, which is stored in the context passed
+// to tasks and can be read with NameFromContext.
+func WithName(name string) Option {
+	return func(r *Retrier) {
+		r.name = name
+	}
+}
+
+// WithAbort sets a channel that stops the retrier from retrying tasks when it
+// is closed, without canceling the context that the tasks use. Attempts in
+// flight are not interrupted, but the retrier stops with ErrAborted instead of
+// retrying them, and waiting between retries is cut short.
+func WithAbort(abort <-chan struct{}) Option {
+	return func(r *Retrier) {
+		r.abort = abort
+	}
+}
+
+// WithClock sets the source of time that the retrier uses to measure elapsed
+// time and to wait between retries, such as a fake clock in tests.
+func WithClock(clock Clock) Option {
+	return func(r *Retrier) {
+		r.clock = clock
+	}
+}
+
+// WithRandSource sets the source of random numbers that the retrier uses for
+// randomized behavior such as jitter. If the source is nil, the default source
+// is used.
+func WithRandSource(src rand.Source) Option {
+	return func(r *Retrier) {
+		r.rand = newRand(src)
+	}
+}
+
+// WithAttemptTimeout limits the duration of each attempt of a task, so that a
+// hung attempt is canceled and retried instead of blocking the run until the
+// deadline of the parent context. When combined with proportional attempt
+// timeouts, the shorter of the two timeouts is used.
+func WithAttemptTimeout(d time.Duration) Option {
+	return func(r *Retrier) {
+		r.attemptTimeout = d
+	}
+}
+
+// WithProportionalAttemptTimeout gives each attempt a fair share of the time
+// remaining until the deadline of the context, so that a slow attempt can not
+// consume the whole budget. The timeout of each attempt is calculated by
+// (remaining/(max+1-retries)), the remaining time divided by the number of
+// attempts left. Without a deadline or a limit of retries, the share can not be
+// computed, and the default slice is used instead.
+func WithProportionalAttemptTimeout(enabled bool) Option {
+	return func(r *Retrier) {
+		r.proportional = enabled
+	}
+}
+
+// WithDefaultAttemptSlice sets the timeout of each attempt for proportional
+// attempt timeouts when the share of the remaining time can not be computed.
+// The slice is disabled when the value is not positive.
+func WithDefaultAttemptSlice(d time.Duration) Option {
+	return func(r *Retrier) {
+		r.defaultSlice = d
+	}
+}
+
+// WithDeadlineClamp makes the retrier aware of the deadline of the context.
+// When the delay before the next retry would not end before the deadline, no
+// attempt could run in time, so the retrier stops right away with
+// ErrDeadlineTooShort instead of sleeping until the deadline is exceeded.
+// Delays that end before the deadline are not changed.
+func WithDeadlineClamp(enabled bool) Option {
+	return func(r *Retrier) {
+		r.clamp = enabled
+	}
+}
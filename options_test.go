@@ -0,0 +1,1072 @@
+package retrier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWithDelayRounding tests if the delays of a retrier are rounded to the
+// nearest multiple of the configured duration
+func TestWithDelayRounding(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Rounding time.Duration
+		DelayIn  time.Duration
+		DelayOut time.Duration
+	}{
+		{
+			Name:     "Round down",
+			Rounding: time.Millisecond * 100,
+			DelayIn:  time.Millisecond * 1047,
+			DelayOut: time.Second,
+		},
+		{
+			Name:     "Round up",
+			Rounding: time.Millisecond * 100,
+			DelayIn:  time.Millisecond * 1063,
+			DelayOut: time.Millisecond * 1100,
+		},
+		{
+			Name:     "Rounding disabled",
+			Rounding: 0,
+			DelayIn:  time.Millisecond * 1047,
+			DelayOut: time.Millisecond * 1047,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				5,
+				ConstantDelay(test.DelayIn),
+				WithDelayRounding(test.Rounding),
+			)
+
+			assert.Equal(t, test.DelayOut, retr.delay(0, nil))
+		})
+	}
+}
+
+// TestWithHealthGate tests if the retrier polls the health gate until it
+// reports healthy before retrying a task, and stops when the gate does not
+// open within its max wait
+func TestWithHealthGate(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Unhealthy int
+		Timeout   time.Duration
+		MaxWait   time.Duration
+		Polls     int
+		Attempts  int
+		Elapsed   time.Duration
+		Error     error
+	}{
+		{
+			Name:      "Gate opens after two polls",
+			Unhealthy: 2,
+			Timeout:   time.Millisecond * 100,
+			MaxWait:   time.Second,
+			Polls:     3,
+			Attempts:  2,
+			Elapsed:   time.Millisecond * 10,
+			Error:     nil,
+		},
+		{
+			Name:      "Context times out while gate is closed",
+			Unhealthy: 1000,
+			Timeout:   time.Millisecond * 20,
+			MaxWait:   time.Second,
+			Attempts:  1,
+			Elapsed:   time.Millisecond * 20,
+			Error:     context.DeadlineExceeded,
+		},
+		{
+			Name:      "Gate does not open within max wait",
+			Unhealthy: 1000,
+			Timeout:   time.Millisecond * 500,
+			MaxWait:   time.Millisecond * 20,
+			Attempts:  1,
+			Elapsed:   time.Millisecond * 20,
+			Error:     fmt.Errorf("failed after health gate timeout: error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			polls, attempts := 0, 0
+			retr := NewRetrier(
+				5,
+				NoDelay(),
+				WithHealthGate(
+					func(ctx context.Context) bool {
+						polls++
+						return polls > test.Unhealthy
+					},
+					time.Millisecond*5,
+					test.MaxWait,
+				),
+			)
+
+			ctx, cncl := context.WithTimeout(context.TODO(), test.Timeout)
+			defer cncl()
+
+			var err error
+			ch := make(chan bool)
+			st := time.Now()
+			go func() {
+				err = retr.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+					attempts++
+					if attempts < 2 {
+						return fmt.Errorf("error"), true
+					}
+					return nil, false
+				})
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+			dif := time.Since(st)
+
+			if test.Error != nil {
+				assert.EqualError(t, err, test.Error.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.Polls, polls)
+			}
+
+			assert.Equal(t, test.Attempts, attempts)
+			assert.GreaterOrEqual(t, dif, test.Elapsed)
+		})
+	}
+}
+
+// TestWithHealthGateDefaults tests if intervals and waits of the health gate
+// that are not positive are replaced with the defaults
+func TestWithHealthGateDefaults(t *testing.T) {
+	retr := NewRetrier(1, NoDelay(), WithHealthGate(
+		func(ctx context.Context) bool { return true }, 0, -time.Second,
+	))
+
+	assert.Equal(t, DefaultHealthPoll, retr.poll)
+	assert.Equal(t, DefaultHealthMaxWait, retr.healthWait)
+}
+
+// TestWithMaxElapsedTime tests if the retrier stops retrying a task when the
+// next attempt would start after the elapsed time limit, and returns an error
+// that records the attempts and the elapsed time
+func TestWithMaxElapsedTime(t *testing.T) {
+	tests := []struct {
+		Name       string
+		MaxElapsed time.Duration
+		Attempts   int
+		Elapsed    time.Duration
+		Error      error
+	}{
+		{
+			Name:       "Slow task exceeds limit",
+			MaxElapsed: time.Millisecond * 35,
+			Attempts:   3,
+			Elapsed:    time.Millisecond * 40,
+			Error:      fmt.Errorf("failed after max elapsed time: error"),
+		},
+		{
+			Name:       "Limit disabled",
+			MaxElapsed: 0,
+			Attempts:   6,
+			Elapsed:    time.Millisecond * 85,
+			Error:      fmt.Errorf("failed after max retries: error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				5,
+				ConstantDelay(time.Millisecond*5),
+				WithMaxElapsedTime(test.MaxElapsed),
+			)
+
+			var res Result
+			var err error
+			ch := make(chan bool)
+			go func() {
+				res, err = retr.RunResult(func() (error, bool) {
+					time.Sleep(time.Millisecond * 10)
+					return fmt.Errorf("error"), true
+				})
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			assert.EqualError(t, err, test.Error.Error())
+			if test.MaxElapsed > 0 {
+				assert.ErrorIs(t, err, ErrMaxElapsedTime)
+
+				var exh *ExhaustedError
+				if assert.ErrorAs(t, err, &exh) {
+					assert.Equal(t, test.Attempts, exh.Attempts)
+					assert.Equal(t, res.Elapsed, exh.Elapsed)
+				}
+			}
+
+			assert.Equal(t, test.Attempts, res.Attempts)
+			assert.GreaterOrEqual(t, res.Elapsed, test.Elapsed)
+		})
+	}
+}
+
+// TestWithMaxTotalSleep tests if the retrier stops retrying a task when the
+// total time spent sleeping would exceed the limit, regardless of the time
+// spent executing the task
+func TestWithMaxTotalSleep(t *testing.T) {
+	tests := []struct {
+		Name     string
+		MaxSleep time.Duration
+		Task     time.Duration
+		Attempts int
+		Elapsed  time.Duration
+		Error    error
+	}{
+		{
+			Name:     "Slow task with small delays",
+			MaxSleep: time.Millisecond * 3,
+			Task:     time.Millisecond * 10,
+			Attempts: 4,
+			Elapsed:  time.Millisecond * 40,
+			Error:    fmt.Errorf("failed after max total sleep: error"),
+		},
+		{
+			Name:     "Limit disabled",
+			MaxSleep: 0,
+			Task:     0,
+			Attempts: 6,
+			Elapsed:  time.Millisecond * 5,
+			Error:    fmt.Errorf("failed after max retries: error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				5,
+				ConstantDelay(time.Millisecond),
+				WithMaxTotalSleep(test.MaxSleep),
+			)
+
+			var res Result
+			var err error
+			ch := make(chan bool)
+			go func() {
+				res, err = retr.RunResult(func() (error, bool) {
+					time.Sleep(test.Task)
+					return fmt.Errorf("error"), true
+				})
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			assert.EqualError(t, err, test.Error.Error())
+			if test.MaxSleep > 0 {
+				assert.ErrorIs(t, err, ErrMaxTotalSleep)
+			}
+
+			assert.Equal(t, test.Attempts, res.Attempts)
+			assert.GreaterOrEqual(t, res.Elapsed, test.Elapsed)
+		})
+	}
+}
+
+// TestWithCooldownFor tests if the delay is overridden with the cooldown of the
+// first rule that matches the error, and the delay function is used otherwise
+func TestWithCooldownFor(t *testing.T) {
+	errRateLimit := fmt.Errorf("rate limited")
+	errNetwork := fmt.Errorf("network error")
+	errThrottled := fmt.Errorf("throttled: %w", errRateLimit)
+
+	tests := []struct {
+		Name  string
+		Error error
+		Delay time.Duration
+	}{
+		{
+			Name:  "Rate limit error uses cooldown",
+			Error: errRateLimit,
+			Delay: time.Minute,
+		},
+		{
+			Name:  "First matching rule wins",
+			Error: errThrottled,
+			Delay: time.Minute,
+		},
+		{
+			Name:  "Network error uses backoff",
+			Error: errNetwork,
+			Delay: time.Second,
+		},
+		{
+			Name:  "Requested delay takes precedence",
+			Error: RetryAfter(errRateLimit, time.Second*30),
+			Delay: time.Second * 30,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				5,
+				ConstantDelay(time.Second),
+				WithCooldownFor(
+					func(err error) bool {
+						return errors.Is(err, errRateLimit)
+					},
+					time.Minute,
+				),
+				WithCooldownFor(
+					func(err error) bool {
+						return err == errThrottled
+					},
+					time.Hour,
+				),
+			)
+
+			assert.Equal(t, test.Delay, retr.delay(0, test.Error))
+		})
+	}
+}
+
+// TestWithJitterAboveThreshold tests if delays below the threshold are exact,
+// while delays above the threshold are jittered within the fraction
+func TestWithJitterAboveThreshold(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Delay    time.Duration
+		Jittered bool
+	}{
+		{
+			Name:     "Delay below threshold",
+			Delay:    time.Millisecond * 100,
+			Jittered: false,
+		},
+		{
+			Name:     "Delay at threshold",
+			Delay:    time.Second,
+			Jittered: false,
+		},
+		{
+			Name:     "Delay above threshold",
+			Delay:    time.Second * 10,
+			Jittered: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				5,
+				ConstantDelay(test.Delay),
+				WithJitterAboveThreshold(time.Second, 0.5),
+			)
+
+			changed := false
+			for i := 0; i < 100; i++ {
+				dur := retr.delay(0, nil)
+				if test.Jittered {
+					assert.GreaterOrEqual(t, dur, test.Delay/2)
+					assert.LessOrEqual(t, dur, test.Delay*3/2)
+				} else {
+					assert.Equal(t, test.Delay, dur)
+				}
+				changed = changed || dur != test.Delay
+			}
+
+			assert.Equal(t, test.Jittered, changed)
+		})
+	}
+}
+
+// TestWithStartupJitter tests if the first attempt of a run is delayed by a
+// random amount that is bounded by the maximum offset
+func TestWithStartupJitter(t *testing.T) {
+	tests := []struct {
+		Name      string
+		MaxOffset time.Duration
+		Seed      int64
+	}{
+		{
+			Name:      "Offset from first seed",
+			MaxOffset: time.Millisecond * 50,
+			Seed:      1,
+		},
+		{
+			Name:      "Offset from second seed",
+			MaxOffset: time.Millisecond * 50,
+			Seed:      2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				5,
+				NoDelay(),
+				WithStartupJitter(test.MaxOffset),
+				WithRandSource(rand.NewSource(test.Seed)),
+			)
+			rnd := rand.New(rand.NewSource(test.Seed))
+			offset := time.Duration(rnd.Float64() * float64(test.MaxOffset))
+
+			var dif time.Duration
+			ch := make(chan bool)
+			st := time.Now()
+			go func() {
+				retr.Run(func() (error, bool) {
+					dif = time.Since(st)
+					return nil, false
+				})
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			assert.GreaterOrEqual(t, dif, offset)
+			assert.Less(t, dif, test.MaxOffset*2)
+		})
+	}
+}
+
+// TestWithProportionalAttemptTimeout tests if each attempt gets a share of the
+// time remaining until the deadline, recalculated before every attempt
+func TestWithProportionalAttemptTimeout(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Max     int
+		Timeout time.Duration
+		Slices  []time.Duration
+	}{
+		{
+			Name:    "Slices are recalculated",
+			Max:     3,
+			Timeout: time.Millisecond * 400,
+			Slices: []time.Duration{
+				time.Millisecond * 100,
+				time.Millisecond * 350 / 3,
+				time.Millisecond * 150,
+				time.Millisecond * 250,
+			},
+		},
+		{
+			Name:    "Unlimited retries use default slice",
+			Max:     -1,
+			Timeout: time.Millisecond * 400,
+			Slices: []time.Duration{
+				time.Millisecond * 80,
+				time.Millisecond * 80,
+			},
+		},
+		{
+			Name:    "No deadline uses default slice",
+			Max:     1,
+			Timeout: 0,
+			Slices: []time.Duration{
+				time.Millisecond * 80,
+				time.Millisecond * 80,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				test.Max,
+				NoDelay(),
+				WithProportionalAttemptTimeout(true),
+				WithDefaultAttemptSlice(time.Millisecond*80),
+			)
+
+			ctx := context.TODO()
+			if test.Timeout > 0 {
+				var cncl context.CancelFunc
+				ctx, cncl = context.WithTimeout(ctx, test.Timeout)
+				defer cncl()
+			}
+
+			slices := []time.Duration{}
+			retr.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+				dl, ok := ctx.Deadline()
+				assert.True(t, ok)
+				slices = append(slices, time.Until(dl))
+				time.Sleep(time.Millisecond * 50)
+				return fmt.Errorf("error"), len(slices) < len(test.Slices)
+			})
+
+			if assert.Len(t, slices, len(test.Slices)) {
+				for i := range slices {
+					assert.InDelta(
+						t,
+						float64(test.Slices[i]),
+						float64(slices[i]),
+						float64(time.Millisecond*15),
+					)
+				}
+			}
+		})
+	}
+}
+
+// TestWithAttemptTimeout tests if each attempt is canceled after the attempt
+// timeout, and the shorter timeout is used when combined with proportional
+// attempt timeouts
+func TestWithAttemptTimeout(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Timeout      time.Duration
+		Proportional bool
+		Slices       []time.Duration
+	}{
+		{
+			Name:         "Hung attempts are canceled",
+			Timeout:      time.Millisecond * 20,
+			Proportional: false,
+			Slices: []time.Duration{
+				time.Millisecond * 20,
+				time.Millisecond * 20,
+			},
+		},
+		{
+			Name:         "Shorter than proportional timeout",
+			Timeout:      time.Millisecond * 20,
+			Proportional: true,
+			Slices: []time.Duration{
+				time.Millisecond * 20,
+				time.Millisecond * 20,
+			},
+		},
+		{
+			Name:         "Longer than proportional timeout",
+			Timeout:      time.Millisecond * 300,
+			Proportional: true,
+			Slices: []time.Duration{
+				time.Millisecond * 200,
+				time.Millisecond * 200,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				1,
+				NoDelay(),
+				WithAttemptTimeout(test.Timeout),
+				WithProportionalAttemptTimeout(test.Proportional),
+			)
+
+			ctx := context.TODO()
+			if test.Proportional {
+				var cncl context.CancelFunc
+				ctx, cncl = context.WithTimeout(ctx, time.Millisecond*400)
+				defer cncl()
+			}
+
+			var err error
+			slices := []time.Duration{}
+			ch := make(chan bool)
+			go func() {
+				err = retr.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+					dl, ok := ctx.Deadline()
+					assert.True(t, ok)
+					slices = append(slices, time.Until(dl))
+					<-ctx.Done()
+					return ctx.Err(), true
+				})
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			if assert.Len(t, slices, len(test.Slices)) {
+				for i := range slices {
+					assert.InDelta(
+						t,
+						float64(test.Slices[i]),
+						float64(slices[i]),
+						float64(time.Millisecond*15),
+					)
+				}
+			}
+		})
+	}
+}
+
+// TestWithDeadlineClamp tests if the retrier stops immediately when the next
+// delay would not end before the deadline of the context
+func TestWithDeadlineClamp(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Clamp    bool
+		Delay    time.Duration
+		Attempts int
+		Elapsed  time.Duration
+		Error    error
+	}{
+		{
+			Name:     "Delay exceeds deadline",
+			Clamp:    true,
+			Delay:    time.Millisecond * 500,
+			Attempts: 1,
+			Elapsed:  0,
+			Error:    fmt.Errorf("failed before deadline: error"),
+		},
+		{
+			Name:     "Delay fits within deadline",
+			Clamp:    true,
+			Delay:    time.Millisecond * 20,
+			Attempts: 3,
+			Elapsed:  time.Millisecond * 40,
+			Error:    fmt.Errorf("failed before deadline: error"),
+		},
+		{
+			Name:     "Clamp disabled",
+			Clamp:    false,
+			Delay:    time.Millisecond * 500,
+			Attempts: 1,
+			Elapsed:  time.Millisecond * 50,
+			Error:    context.DeadlineExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				-1,
+				ConstantDelay(test.Delay),
+				WithDeadlineClamp(test.Clamp),
+			)
+
+			ctx, cncl := context.WithTimeout(
+				context.TODO(),
+				time.Millisecond*50,
+			)
+			defer cncl()
+
+			var res Result
+			var err error
+			ch := make(chan bool)
+			go func() {
+				res, err = retr.RunCtxResult(ctx, func(ctx context.Context) (error, bool) {
+					return fmt.Errorf("error"), true
+				})
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			assert.EqualError(t, err, test.Error.Error())
+			if test.Clamp {
+				assert.ErrorIs(t, err, ErrDeadlineTooShort)
+				assert.Less(t, res.Elapsed, time.Millisecond*50)
+			}
+
+			assert.Equal(t, test.Attempts, res.Attempts)
+			assert.GreaterOrEqual(t, res.Elapsed, test.Elapsed)
+		})
+	}
+}
+
+// TestWithRetryIf tests if the classifier decides whether a task is retried
+// after failing with an error, regardless of the decision of the task
+func TestWithRetryIf(t *testing.T) {
+	errTimeout := fmt.Errorf("timeout")
+	errInvalid := fmt.Errorf("invalid")
+
+	tests := []struct {
+		Name     string
+		Error    error
+		Retry    bool
+		Attempts int
+	}{
+		{
+			Name:     "Retryable error is retried",
+			Error:    errTimeout,
+			Retry:    false,
+			Attempts: 3,
+		},
+		{
+			Name:     "Fatal error is not retried",
+			Error:    errInvalid,
+			Retry:    true,
+			Attempts: 1,
+		},
+		{
+			Name:     "Success is not classified",
+			Error:    nil,
+			Retry:    false,
+			Attempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				2,
+				NoDelay(),
+				WithRetryIf(func(err error) bool {
+					return errors.Is(err, errTimeout)
+				}),
+			)
+
+			res, err := retr.RunResult(func() (error, bool) {
+				return test.Error, test.Retry
+			})
+
+			assert.ErrorIs(t, err, test.Error)
+			assert.Equal(t, test.Attempts, res.Attempts)
+		})
+	}
+}
+
+// TestWithErrorAggregation tests if the errors of every attempt are joined
+// when the retrier gives up on a task
+func TestWithErrorAggregation(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Aggregate bool
+		Message   string
+	}{
+		{
+			Name:      "Errors are joined",
+			Aggregate: true,
+			Message:   "failed after max retries: error 1\nerror 2\nerror 3",
+		},
+		{
+			Name:      "Last error is wrapped",
+			Aggregate: false,
+			Message:   "failed after max retries: error 3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, NoDelay(), WithErrorAggregation(test.Aggregate))
+
+			errs := []error{}
+			err := retr.Run(func() (error, bool) {
+				err := fmt.Errorf("error %d", len(errs)+1)
+				errs = append(errs, err)
+				return err, true
+			})
+
+			assert.EqualError(t, err, test.Message)
+			assert.ErrorIs(t, err, ErrMaxRetriesExceeded)
+			assert.ErrorIs(t, err, errs[2])
+			if test.Aggregate {
+				assert.ErrorIs(t, err, errs[0])
+				assert.ErrorIs(t, err, errs[1])
+			} else {
+				assert.NotErrorIs(t, err, errs[0])
+			}
+		})
+	}
+}
+
+// TestWithRecoverPanics tests if panics of a task are recovered and converted
+// to errors, and retried according to the configuration
+func TestWithRecoverPanics(t *testing.T) {
+	errPanic := fmt.Errorf("panic error")
+
+	tests := []struct {
+		Name     string
+		Value    any
+		Retry    bool
+		Attempts int
+		Message  string
+	}{
+		{
+			Name:     "Panic is retried",
+			Value:    "boom",
+			Retry:    true,
+			Attempts: 3,
+			Message:  "failed after max retries: panic: boom",
+		},
+		{
+			Name:     "Panic is fatal",
+			Value:    "boom",
+			Retry:    false,
+			Attempts: 1,
+			Message:  "panic: boom",
+		},
+		{
+			Name:     "Panic with error",
+			Value:    errPanic,
+			Retry:    false,
+			Attempts: 1,
+			Message:  "panic: panic error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(
+				2,
+				NoDelay(),
+				WithRecoverPanics(test.Retry),
+				WithRetryIf(func(error) bool {
+					return !test.Retry
+				}),
+			)
+
+			attempts := 0
+			err := retr.Run(func() (error, bool) {
+				attempts++
+				panic(test.Value)
+			})
+
+			var pnc *PanicError
+			if assert.ErrorAs(t, err, &pnc) {
+				assert.Equal(t, test.Value, pnc.Value)
+				assert.Contains(t, string(pnc.Stack), "TestWithRecoverPanics")
+			}
+			assert.True(t, strings.HasPrefix(err.Error(), test.Message))
+			if perr, ok := test.Value.(error); ok {
+				assert.ErrorIs(t, err, perr)
+			}
+			assert.Equal(t, test.Attempts, attempts)
+		})
+	}
+}
+
+// TestWithOnRetry tests if the hook is called before every retry with the
+// failed attempt, its error and the next delay
+func TestWithOnRetry(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Fails    int
+		Attempts []int
+		Delays   []time.Duration
+	}{
+		{
+			Name:     "Retries until success",
+			Fails:    2,
+			Attempts: []int{1, 2},
+			Delays:   []time.Duration{time.Millisecond, time.Millisecond * 2},
+		},
+		{
+			Name:     "No retries after max retries",
+			Fails:    5,
+			Attempts: []int{1, 2, 3},
+			Delays: []time.Duration{
+				time.Millisecond,
+				time.Millisecond * 2,
+				time.Millisecond * 3,
+			},
+		},
+		{
+			Name:     "No retries on success",
+			Fails:    0,
+			Attempts: []int{},
+			Delays:   []time.Duration{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			attempts := []int{}
+			delays := []time.Duration{}
+			retr := NewRetrier(
+				3,
+				LinearDelay(time.Millisecond),
+				WithOnRetry(func(attempt int, err error, delay time.Duration) {
+					assert.EqualError(t, err, fmt.Sprintf("error %d", attempt))
+					attempts = append(attempts, attempt)
+					delays = append(delays, delay)
+				}),
+			)
+
+			count := 0
+			retr.Run(func() (error, bool) {
+				count++
+				if count <= test.Fails {
+					return fmt.Errorf("error %d", count), true
+				}
+				return nil, false
+			})
+
+			assert.Equal(t, test.Attempts, attempts)
+			assert.Equal(t, test.Delays, delays)
+		})
+	}
+}
+
+// TestWithAbort tests if closing the abort channel stops the retrier from
+// retrying without canceling the attempt in flight, and cuts waiting short
+func TestWithAbort(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Delay    time.Duration
+		Attempts int
+		Elapsed  time.Duration
+	}{
+		{
+			Name:     "Abort during attempt",
+			Delay:    time.Millisecond,
+			Attempts: 1,
+			Elapsed:  time.Millisecond * 20,
+		},
+		{
+			Name:     "Abort during delay",
+			Delay:    time.Minute,
+			Attempts: 1,
+			Elapsed:  time.Millisecond * 20,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			abort := make(chan struct{})
+			retr := NewRetrier(5, ConstantDelay(test.Delay), WithAbort(abort))
+
+			go func() {
+				time.Sleep(time.Millisecond * 10)
+				close(abort)
+			}()
+
+			var res Result
+			var err error
+			ch := make(chan bool)
+			go func() {
+				res, err = retr.RunCtxResult(
+					context.TODO(),
+					func(ctx context.Context) (error, bool) {
+						if test.Delay < time.Minute {
+							time.Sleep(time.Millisecond * 20)
+						}
+						assert.NoError(t, ctx.Err())
+						return fmt.Errorf("error"), true
+					},
+				)
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			assert.EqualError(t, err, "failed after abort: error")
+			assert.ErrorIs(t, err, ErrAborted)
+			assert.Equal(t, test.Attempts, res.Attempts)
+			assert.Less(t, res.Elapsed, test.Elapsed+time.Millisecond*20)
+		})
+	}
+}
+
+// TestWithMaxAttempts tests if the limit of retries is set by the total number
+// of attempts of a task
+func TestWithMaxAttempts(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Max      int
+		Attempts int
+	}{
+		{
+			Name:     "Multiple attempts",
+			Max:      3,
+			Attempts: 3,
+		},
+		{
+			Name:     "Single attempt",
+			Max:      1,
+			Attempts: 1,
+		},
+		{
+			Name:     "No limit",
+			Max:      0,
+			Attempts: 10,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(5, NoDelay(), WithMaxAttempts(test.Max))
+
+			count := 0
+			res, _ := retr.RunResult(func() (error, bool) {
+				count++
+				return fmt.Errorf("error"), count < 10
+			})
+
+			assert.Equal(t, test.Attempts, res.Attempts)
+		})
+	}
+}
+
+// TestWithErrorHistory tests if the errors of every attempt are only kept in
+// the exhausted error when the history is enabled
+func TestWithErrorHistory(t *testing.T) {
+	tests := []struct {
+		Name    string
+		History bool
+		Errors  []string
+	}{
+		{
+			Name:    "History enabled",
+			History: true,
+			Errors:  []string{"error 1", "error 2", "error 3"},
+		},
+		{
+			Name:    "History disabled",
+			History: false,
+			Errors:  nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(2, NoDelay(), WithErrorHistory(test.History))
+
+			count := 0
+			err := retr.Run(func() (error, bool) {
+				count++
+				return fmt.Errorf("error %d", count), true
+			})
+
+			var exh *ExhaustedError
+			if assert.ErrorAs(t, err, &exh) {
+				var msgs []string
+				for _, err := range exh.Errors {
+					msgs = append(msgs, err.Error())
+				}
+				assert.Equal(t, test.Errors, msgs)
+				assert.EqualError(t, exh.LastErr, "error 3")
+			}
+		})
+	}
+}
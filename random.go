@@ -0,0 +1,53 @@
+package retrier
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedSource is a random source that is safe for concurrent use, since
+// delay functions may be called from multiple goroutines at the same time.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer from the source.
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+// Seed initializes the source to a deterministic state.
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// newRand returns a function that generates random numbers in [0.0, 1.0) from
+// a source, or from the default source of the package if the source is nil.
+func newRand(src rand.Source) func() float64 {
+	if src == nil {
+		return rand.Float64
+	}
+	return rand.New(&lockedSource{src: src}).Float64
+}
+
+// newNormRand returns a function that generates normally distributed random
+// numbers with a mean of 0 and a standard deviation of 1 from a source, or from
+// the default source of the package if the source is nil.
+func newNormRand(src rand.Source) func() float64 {
+	if src == nil {
+		return rand.NormFloat64
+	}
+	return rand.New(&lockedSource{src: src}).NormFloat64
+}
+
+// jitter randomly perturbs a duration by up to some fraction of it in either
+// direction, using a random number in [0.0, 1.0).
+func jitter(dur time.Duration, fraction float64, rnd float64) time.Duration {
+	return toDuration(float64(dur) * (1 + fraction*(2*rnd-1)))
+}
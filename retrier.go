@@ -2,38 +2,293 @@ package retrier
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"runtime/debug"
+	"sync"
 	"time"
 )
 
+// DelayFunc returns some amount of duration to wait before retrying a task
+// after some number of retries, starting from 0.
+type DelayFunc func(int) time.Duration
+
+// Capped returns a delay function that creates the delays of the delay
+// function up to a specific limit where delay can not be longer.
+func (f DelayFunc) Capped(max time.Duration) DelayFunc {
+	return MinOf(f, ConstantDelay(max))
+}
+
+// Jittered returns a delay function that randomly perturbs the delays of the
+// delay function by up to some fraction of the delay in either direction.
+func (f DelayFunc) Jittered(fraction float64) DelayFunc {
+	return WithJitter(f, fraction)
+}
+
+// Preview returns the first n delays of the delay function, which is useful
+// for inspecting a policy. Stateful or random delay functions produce a sample
+// of their delays, and calling Preview advances their state.
+func (f DelayFunc) Preview(n int) []time.Duration {
+	delays := make([]time.Duration, n)
+	for i := range delays {
+		delays[i] = f(i)
+	}
+	return delays
+}
+
 // Retrier controls how to to run the retry function. A task will be retried
 // up to a set retry count with some delay between the retries defined by a
 // delay function.
 type Retrier struct {
 	// max is the upper limit of retries. The task can not be retried more than
-	// the specified number. To disable the limit, set -1 as the value.
+	// the specified number. To disable the limit, set NoLimit as the value. To
+	// disable retries, set 0 as the value, in which case the task runs once
+	// and its error is returned as it is.
 	max int
 
 	// delayf returns some amount of duration to wait before retrying a task.
 	// The function takes the retry count as a parameter to allow for increasing
 	// delay between retries.
-	delayf func(int) time.Duration
+	delayf DelayFunc
+
+	// backoff is the stateful source of delays that is reset at the start of
+	// every run, if the retrier was created with one.
+	backoff Backoff
+
+	// rounding is the multiple that delays are rounded to before sleeping.
+	// Rounding is disabled when the value is not positive.
+	rounding time.Duration
+
+	// healthf reports whether the dependency of a task is healthy. When set,
+	// the retrier waits for the dependency to become healthy before retrying.
+	healthf func(context.Context) bool
+
+	// poll is the duration to wait between checks of the health function.
+	poll time.Duration
+
+	// healthWait is the longest wait for the health function to report
+	// healthy before the retrier stops.
+	healthWait time.Duration
+
+	// maxSleep is the upper limit of the total time spent sleeping between
+	// retries. The limit is disabled when the value is not positive.
+	maxSleep time.Duration
+
+	// attemptTimeout is the timeout of each attempt of a task. The timeout is
+	// disabled when the value is not positive.
+	attemptTimeout time.Duration
+
+	// maxElapsed is the upper limit of the total elapsed time of a run. The
+	// limit is disabled when the value is not positive.
+	maxElapsed time.Duration
+
+	// ledger persists the record of each attempt of a task.
+	ledger func(context.Context, AttemptRecord) error
+
+	// ledgerPolicy defines what to do when writing to the ledger fails.
+	ledgerPolicy LedgerPolicy
+
+	// cooldowns are rules that override the delay for specific errors.
+	cooldowns []cooldownRule
+
+	// jitterMin is the shortest delay that jitter is applied to.
+	jitterMin time.Duration
+
+	// jitterFrac is the fraction of the delay that jitter can add or remove.
+	// Jitter is disabled when the value is not positive.
+	jitterFrac float64
+
+	// metaf returns metadata for an attempt that is attached to its record.
+	metaf func(context.Context, int) map[string]any
+
+	// onRetry is called with the failed attempt before sleeping to retry it.
+	onRetry func(int, error, time.Duration)
+
+	// clock is the source of time of the retrier. The real time is used when
+	// the clock is nil.
+	clock Clock
+
+	// budget limits the rate of retries across the retriers that share it.
+	budget *Budget
+
+	// name identifies the retrier in the contexts of attempts.
+	name string
+
+	// abort stops the retrier from retrying tasks when it is closed.
+	abort <-chan struct{}
+
+	// onSuccess is called with the result of a run when the task succeeds.
+	onSuccess func(Result)
+
+	// onGiveUp is called with the reason, the result and the error of a run
+	// when the task does not succeed.
+	onGiveUp func(GiveUpReason, Result, error)
+
+	// observers are notified of the attempts and runs of the retrier.
+	observers []Observer
+
+	// interceptors wrap every attempt of a task, with the first one being
+	// the outermost.
+	interceptors []Interceptor
+
+	// recoverPanics recovers panics of a task and converts them to errors.
+	recoverPanics bool
+
+	// retryPanics decides whether a task is retried after a recovered panic.
+	retryPanics bool
+
+	// aggregate joins the errors of every attempt of a task when the retrier
+	// gives up on it.
+	aggregate bool
+
+	// history keeps the errors of every attempt of a task for the
+	// *ExhaustedError when the retrier gives up on it.
+	history bool
+
+	// retryIf decides whether a task is retried after failing with an error,
+	// instead of the decision of the task.
+	retryIf func(error) bool
+
+	// kindPolicies decide whether tasks of some kind of operation may be
+	// retried after failing with an error.
+	kindPolicies map[OpKind]func(error) bool
+
+	// startJitter is the longest random delay before the first attempt.
+	startJitter time.Duration
+
+	// proportional is whether each attempt gets a fair share of the time
+	// remaining until the deadline of the context as its timeout.
+	proportional bool
+
+	// defaultSlice is the timeout of each attempt when the share of the
+	// remaining time can not be computed.
+	defaultSlice time.Duration
+
+	// clamp is whether the retrier stops immediately instead of sleeping when
+	// the next delay would not end before the deadline of the context.
+	clamp bool
+
+	// rand generates random numbers in [0.0, 1.0) for randomized behavior.
+	rand func() float64
+
+	// drain tracks the runs in flight and whether new runs are accepted.
+	drain *drainState
+
+	// stats holds the counters of the runs of the retrier.
+	stats *statsState
+
+	// inner is the retrier that runs the task within each attempt of this
+	// retrier when the retriers are chained.
+	inner *Retrier
 }
 
-// NewRetrier creates a retrier from max retries and a delay function.
+// cooldownRule is a rule that overrides the delay before the next retry with a
+// fixed duration when the error of the task matches a predicate.
+type cooldownRule struct {
+	pred func(error) bool
+	dur  time.Duration
+}
+
+// NoLimit is the value of max retries that disables the limit, so the task is
+// retried until it succeeds, decides not to retry, or the context is canceled.
+const NoLimit = -1
+
+// NewRetrier creates a retrier from max retries, a delay function and
+// optional configuration. A nil delay function is replaced with NoDelay.
 func NewRetrier(
 	max int,
-	delayf func(int) time.Duration,
+	delayf DelayFunc,
+	opts ...Option,
 ) *Retrier {
-	return &Retrier{
+	if delayf == nil {
+		delayf = NoDelay()
+	}
+
+	r := &Retrier{
 		max:    max,
 		delayf: delayf,
+		rand:   newRand(nil),
+		drain:  &drainState{},
+		stats:  &statsState{},
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
+}
+
+// NewRetrierE creates a retrier the same way as NewRetrier, but validates the
+// configuration first, and returns an error wrapping ErrInvalidConfig if the
+// delay function is nil, the limit of retries is below NoLimit, a duration of
+// the options is negative or the jitter fraction is not between 0 and 1. The
+// first delay of the delay function is computed to reject negative delays,
+// such as those of ConstantDelay with a negative duration.
+func NewRetrierE(
+	max int,
+	delayf DelayFunc,
+	opts ...Option,
+) (*Retrier, error) {
+	if delayf == nil {
+		return nil, fmt.Errorf("%w: delay function is nil", ErrInvalidConfig)
+	} else if d := delayf(0); d < 0 {
+		return nil, fmt.Errorf(
+			"%w: delay %v is negative",
+			ErrInvalidConfig, d,
+		)
+	}
+
+	r := NewRetrier(max, delayf, opts...)
+	if r.max < NoLimit {
+		return nil, fmt.Errorf(
+			"%w: max retries %d is below NoLimit",
+			ErrInvalidConfig, r.max,
+		)
+	} else if r.jitterFrac < 0 || r.jitterFrac > 1 {
+		return nil, fmt.Errorf(
+			"%w: jitter fraction %v is not between 0 and 1",
+			ErrInvalidConfig, r.jitterFrac,
+		)
+	}
+
+	type duration struct {
+		name string
+		dur  time.Duration
+	}
+	durations := []duration{
+		{"rounding", r.rounding},
+		{"max total sleep", r.maxSleep},
+		{"attempt timeout", r.attemptTimeout},
+		{"max elapsed time", r.maxElapsed},
+		{"jitter threshold", r.jitterMin},
+		{"start jitter", r.startJitter},
+		{"default attempt timeout", r.defaultSlice},
+	}
+	for _, cd := range r.cooldowns {
+		durations = append(durations, duration{"cooldown", cd.dur})
+	}
+	for _, d := range durations {
+		if d.dur < 0 {
+			return nil, fmt.Errorf(
+				"%w: %s %v is negative",
+				ErrInvalidConfig, d.name, d.dur,
+			)
+		}
+	}
+	return r, nil
+}
+
+// NewForeverRetrier creates a retrier without a limit of retries from a delay
+// function and optional configuration.
+func NewForeverRetrier(
+	delayf DelayFunc,
+	opts ...Option,
+) *Retrier {
+	return NewRetrier(NoLimit, delayf, opts...)
 }
 
 // NoDelay returns a delay function that has no delay between retries.
-func NoDelay() func(int) time.Duration {
+func NoDelay() DelayFunc {
 	return func(retries int) time.Duration {
 		return 0
 	}
@@ -43,7 +298,7 @@ func NoDelay() func(int) time.Duration {
 // between retries. The delay will be the same between the retries.
 func ConstantDelay(
 	delay time.Duration,
-) func(int) time.Duration {
+) DelayFunc {
 	return func(retries int) time.Duration {
 		return delay
 	}
@@ -53,7 +308,7 @@ func ConstantDelay(
 // wait duration between retries. The delay is calculated by (step*retries).
 func LinearDelay(
 	step time.Duration,
-) func(int) time.Duration {
+) DelayFunc {
 	return func(retries int) time.Duration {
 		return step + time.Duration(retries)*step
 	}
@@ -65,7 +320,7 @@ func LinearDelay(
 func CappedLinearDelay(
 	step time.Duration,
 	cap time.Duration,
-) func(int) time.Duration {
+) DelayFunc {
 	return func(retries int) time.Duration {
 		delay := step + time.Duration(retries)*step
 		if delay < cap {
@@ -78,39 +333,59 @@ func CappedLinearDelay(
 
 // ExponentialDelay returns a delay function that creates an exponentially
 // increasing wait duration between retries. The delay is calculated by
-// (coef*base^retries).
+// (coef*base^retries), where the coefficient is the initial delay as a
+// duration, like the delays of LinearDelay and ConstantDelay. Delays that
+// would overflow are clamped to the longest representable duration.
 func ExponentialDelay(
 	coef time.Duration,
 	base int,
-) func(int) time.Duration {
+) DelayFunc {
 	return func(retries int) time.Duration {
-		scale := int(math.Pow(float64(base), float64(retries)))
-		return coef * time.Duration(scale)
+		scale := math.Pow(float64(base), float64(retries))
+		return toDuration(float64(coef) * scale)
 	}
 }
 
 // CappedExponentialDelay returns a delay function that creates an exponentially
 // increasing wait duration between retries up to a specific limit where delay
-// can not be longer.. The delay is calculated by (coef*base^retries).
+// can not be longer. The delay is calculated by min(coef*base^retries, cap),
+// where the coefficient is the initial delay as a duration. Delays that would
+// overflow are clamped to the cap.
 func CappedExponentialDelay(
 	coef time.Duration,
 	base int,
 	cap time.Duration,
-) func(int) time.Duration {
+) DelayFunc {
 	return func(retries int) time.Duration {
-		scale := int(math.Pow(float64(base), float64(retries)))
-		delay := coef * time.Duration(scale)
-		if delay <= cap {
-			return delay
+		scale := math.Pow(float64(base), float64(retries))
+		delay := float64(coef) * scale
+		if delay <= float64(cap) {
+			return toDuration(delay)
 		} else {
 			return cap
 		}
 	}
 }
 
+// Result describes how a run of a work task went.
+type Result struct {
+	// Attempts is the number of times the task was executed.
+	Attempts int
+
+	// Elapsed is the total duration of the run, including delays.
+	Elapsed time.Duration
+}
+
 // Run executes a work task with the background context.
 func (r *Retrier) Run(work func() (error, bool)) error {
-	return r.RunCtx(
+	_, err := r.RunResult(work)
+	return err
+}
+
+// RunResult executes a work task with the background context, and returns the
+// result of the run along with the error.
+func (r *Retrier) RunResult(work func() (error, bool)) (Result, error) {
+	return r.RunCtxResult(
 		context.Background(),
 		func(ctx context.Context) (error, bool) {
 			return work()
@@ -125,35 +400,396 @@ func (r *Retrier) RunCtx(
 	ctx context.Context,
 	work func(ctx context.Context) (error, bool),
 ) error {
+	_, err := r.RunCtxResult(ctx, work)
+	return err
+}
+
+// RunErr executes a work task that only returns an error with the background
+// context. The task succeeds when it returns no error. Errors are retried
+// unless they are permanent or the classifier of the retrier decides
+// otherwise.
+func (r *Retrier) RunErr(work func() error) error {
+	return r.RunCtxErr(
+		context.Background(),
+		func(ctx context.Context) error {
+			return work()
+		},
+	)
+}
+
+// RunCtxErr executes a work task that only returns an error in the context of
+// a retrier. The task succeeds when it returns no error. Errors are retried
+// unless they are permanent or the classifier of the retrier decides
+// otherwise.
+func (r *Retrier) RunCtxErr(
+	ctx context.Context,
+	work func(ctx context.Context) error,
+) error {
+	return r.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		err := work(ctx)
+		return err, err != nil
+	})
+}
+
+// RunCtxResult executes a work task the same way as RunCtx, and returns the
+// result of the run along with the error.
+func (r *Retrier) RunCtxResult(
+	ctx context.Context,
+	work func(ctx context.Context) (error, bool),
+) (Result, error) {
+	return r.run(ctx, OpAny, work)
+}
+
+// run executes a work task of some kind of operation in the context of a
+// retrier, and returns the result of the run along with the error.
+func (r *Retrier) run(
+	ctx context.Context,
+	kind OpKind,
+	work func(ctx context.Context) (error, bool),
+) (Result, error) {
+	if !r.enter() {
+		return Result{}, ErrShuttingDown
+	}
+	defer r.leave()
+
+	res, err := r.loop(ctx, kind, work)
+	r.stats.countRun(res, err)
+	reason := giveUpReason(ctx, err)
+	r.notify(reason, res, err)
+	for _, o := range r.observers {
+		if ro, ok := o.(reasonObserver); ok {
+			ro.observeRun(r.name, reason, res, err)
+		} else {
+			o.ObserveRun(r.name, res, err)
+		}
+	}
+	return res, err
+}
+
+// loop executes a work task in the context of a retrier until the task
+// succeeds, the task decides not to retry, or the retrier stops retrying.
+func (r *Retrier) loop(
+	ctx context.Context,
+	kind OpKind,
+	work func(ctx context.Context) (error, bool),
+) (Result, error) {
+	if r.recoverPanics {
+		task := work
+		work = func(ctx context.Context) (err error, ret bool) {
+			defer func() {
+				if v := recover(); v != nil {
+					err = &PanicError{Value: v, Stack: debug.Stack()}
+					ret = r.retryPanics
+				}
+			}()
+			return task(ctx)
+		}
+	}
+
+	if r.inner != nil {
+		task := work
+		work = func(ctx context.Context) (error, bool) {
+			err, exhausted := r.inner.runTracked(ctx, task)
+			return err, exhausted && ctx.Err() == nil
+		}
+	}
+
+	for i := len(r.interceptors) - 1; i >= 0; i-- {
+		task, intercept := work, r.interceptors[i]
+		work = func(ctx context.Context) (error, bool) {
+			return intercept(ctx, task)
+		}
+	}
+
+	if r.backoff != nil {
+		r.backoff.Reset()
+	}
+
 	retries := 0
+	slept := time.Duration(0)
+	prev := time.Duration(0)
+	st := r.now()
+	var last error
+	var errs []error
+	result := func() Result {
+		return Result{
+			Attempts: retries + 1,
+			Elapsed:  r.since(st),
+		}
+	}
+	exhausted := func(reason, err error) (Result, error) {
+		res := result()
+		exh := &ExhaustedError{
+			Reason:   reason,
+			Attempts: res.Attempts,
+			Elapsed:  res.Elapsed,
+			LastErr:  err,
+		}
+		if r.aggregate {
+			exh.LastErr = join(errs...)
+		}
+		if r.history {
+			exh.Errors = errs
+		}
+		return res, exh
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	if r.startJitter > 0 {
+		offset := toDuration(r.rand() * float64(r.startJitter))
+		if err := r.sleep(ctx, offset); err != nil {
+			return Result{Elapsed: r.since(st)}, err
+		}
+	}
 
 	for {
-		err, ret := work(ctx)
-		if !ret {
-			return err
-		} else if r.max != -1 && retries >= r.max {
-			return fmt.Errorf("failed after max retries: %w", err)
+		var meta map[string]any
+		if r.metaf != nil {
+			meta = r.metaf(ctx, retries+1)
+		}
+
+		next := time.Duration(0)
+		if r.max != 0 && (r.max == NoLimit || retries < r.max) {
+			next = r.delayf(retries)
+		}
+
+		ast := r.now()
+		actx, cncl := r.attemptContext(ctx, retries)
+		actx = context.WithValue(actx, attemptKey{}, Attempt{
+			Number:       retries + 1,
+			StartTime:    ast,
+			LastErr:      last,
+			NextDelay:    next,
+			TotalElapsed: ast.Sub(st),
+			Delay:        prev,
+		})
+		if r.name != "" {
+			actx = context.WithValue(actx, nameKey{}, r.name)
+		}
+		if meta != nil {
+			actx = context.WithValue(actx, metadataKey{}, meta)
+		}
+		err, ret := work(actx)
+		cncl()
+		r.stats.attempts.Add(1)
+		last = err
+		if r.aggregate || r.history {
+			errs = append(errs, err)
+		}
+		ret = r.classify(err, ret) && r.allowRetry(kind, err)
+		if r.ledger != nil || len(r.observers) > 0 {
+			rec := AttemptRecord{
+				Attempt:  retries + 1,
+				Start:    ast,
+				Duration: r.since(ast),
+				Err:      err,
+				Retry:    ret,
+				Metadata: meta,
+			}
+			for _, o := range r.observers {
+				o.ObserveAttempt(r.name, rec)
+			}
+			if r.ledger != nil {
+				if lerr := r.record(ctx, rec); lerr != nil {
+					return result(), lerr
+				}
+			}
+		}
+
+		if !ret || r.max == 0 {
+			return result(), err
+		} else if r.max != NoLimit && retries >= r.max {
+			return exhausted(ErrMaxRetriesExceeded, err)
+		} else if r.aborted() {
+			return exhausted(ErrAborted, err)
 		} else {
-			err := sleep(ctx, r.delayf(retries))
-			if err != nil {
-				return err
+			delay := r.override(next, err)
+			if r.maxSleep > 0 && slept+delay > r.maxSleep {
+				return exhausted(ErrMaxTotalSleep, err)
+			}
+			if r.maxElapsed > 0 && r.since(st)+delay > r.maxElapsed {
+				return exhausted(ErrMaxElapsedTime, err)
+			}
+			if dl, ok := ctx.Deadline(); ok && r.clamp && delay >= time.Until(dl) {
+				return exhausted(ErrDeadlineTooShort, err)
+			}
+			if r.budget != nil && !r.budget.Allow() {
+				return exhausted(ErrBudgetExhausted, err)
+			}
+			slept += delay
+			prev = delay
+			r.stats.countRetry(delay)
+
+			if r.onRetry != nil {
+				r.onRetry(retries+1, err, delay)
+			}
+			for _, o := range r.observers {
+				o.ObserveRetry(r.name, retries+1, err, delay)
+			}
+			serr := r.sleep(ctx, delay)
+			if serr == nil && r.healthf != nil {
+				serr = r.waitHealthy(ctx)
+			}
+			if serr == ErrAborted || serr == ErrUnhealthy {
+				return exhausted(serr, err)
+			} else if serr != nil {
+				return result(), serr
 			}
 			retries++
 		}
 	}
 }
 
+// FitsWithin reports whether all attempts of the retrier can complete within
+// a deadline, given the expected duration of the task. The estimate is the sum
+// of the delays between attempts and the durations of the attempts. If the
+// attempts do not fit, the number of attempts that fit is returned as well.
+// A retrier without a limit never fits, and if its attempts take no time at
+// all, the number of attempts that fit is reported as -1.
+//
+// The delays are the un-jittered schedule of the delay function, without the
+// jitter and cooldowns of the retrier, so the estimate draws no random numbers
+// from the source of the retrier and does not change the delays of later runs.
+func (r *Retrier) FitsWithin(
+	deadline time.Duration,
+	expectedTaskDuration time.Duration,
+) (bool, int) {
+	total := time.Duration(0)
+	for attempts := 0; r.max == NoLimit || attempts <= r.max; attempts++ {
+		cost := expectedTaskDuration
+		if attempts > 0 {
+			cost += r.schedule(attempts - 1)
+		}
+		if cost <= 0 && r.max == NoLimit {
+			return false, -1
+		} else if cost > deadline-total {
+			return false, attempts
+		}
+		total += cost
+	}
+	return true, r.max + 1
+}
+
+// attemptContext derives the context of an attempt from the context of the run
+// after some number of retries, applying a timeout to the attempt if needed.
+func (r *Retrier) attemptContext(
+	ctx context.Context,
+	retries int,
+) (context.Context, context.CancelFunc) {
+	slice := time.Duration(0)
+	if r.proportional {
+		slice = r.defaultSlice
+		if dl, ok := ctx.Deadline(); ok && r.max != NoLimit {
+			slice = time.Until(dl) / time.Duration(r.max+1-retries)
+		}
+	}
+	if r.attemptTimeout > 0 && (slice <= 0 || r.attemptTimeout < slice) {
+		slice = r.attemptTimeout
+	}
+	if slice <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, slice)
+}
+
+// delay computes the duration to wait before retrying a task after some
+// number of retries and the error of the last attempt.
+func (r *Retrier) delay(retries int, err error) time.Duration {
+	return r.override(r.delayf(retries), err)
+}
+
+// schedule computes the un-jittered delay before retrying a task after some
+// number of retries, rounded like the delays of runs.
+func (r *Retrier) schedule(retries int) time.Duration {
+	delay := r.delayf(retries)
+	if r.rounding > 0 {
+		delay = delay.Round(r.rounding)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// override adjusts the delay of the delay function with the delay requested
+// by the error of the last attempt, cooldowns, jitter and rounding.
+func (r *Retrier) override(delay time.Duration, err error) time.Duration {
+	var hint *retryAfterError
+	if errors.As(err, &hint) {
+		delay = hint.delay
+	} else {
+		for _, cd := range r.cooldowns {
+			if cd.pred(err) {
+				delay = cd.dur
+				break
+			}
+		}
+		if r.jitterFrac > 0 && delay > r.jitterMin {
+			delay = jitter(delay, r.jitterFrac, r.rand())
+		}
+	}
+
+	if r.rounding > 0 {
+		delay = delay.Round(r.rounding)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// waitHealthy polls the health function until it reports that the dependency
+// is healthy, until the max wait has passed, or until the context has been
+// canceled.
+func (r *Retrier) waitHealthy(ctx context.Context) error {
+	st := r.now()
+	for !r.healthf(ctx) {
+		left := r.healthWait - r.since(st)
+		if left <= 0 {
+			return ErrUnhealthy
+		}
+		poll := r.poll
+		if left < poll {
+			poll = left
+		}
+		if err := r.sleep(ctx, poll); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timers is a pool of stopped timers that can be reused by sleep to avoid
+// allocating a new timer for every delay.
+var timers = sync.Pool{
+	New: func() any {
+		t := time.NewTimer(time.Hour)
+		t.Stop()
+		return t
+	},
+}
+
 // sleep stops the execution for some duration, or until the context has
 // been canceled.
 func sleep(
 	ctx context.Context,
 	dur time.Duration,
 ) error {
-	t := time.After(dur)
+	t := timers.Get().(*time.Timer)
+	t.Reset(dur)
+
 	select {
-	case <-t:
+	case <-t.C:
+		timers.Put(t)
 		return nil
 	case <-ctx.Done():
+		// A timer that fired concurrently might still deliver a value on its
+		// channel, so it is only reused if it was stopped before firing.
+		if t.Stop() {
+			timers.Put(t)
+		}
 		return ctx.Err()
 	}
 }
@@ -2,7 +2,10 @@ package retrier
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 	"time"
 
@@ -238,6 +241,13 @@ func TestExponentialDelay(t *testing.T) {
 			DelayIn:  time.Second,
 			DelayOut: time.Second * 33554432,
 		},
+		{
+			Name:     "Nth call that would overflow",
+			Count:    100,
+			Base:     2,
+			DelayIn:  time.Second,
+			DelayOut: time.Duration(math.MaxInt64),
+		},
 	}
 
 	for _, test := range tests {
@@ -303,6 +313,14 @@ func TestCappedExponentialDelay(t *testing.T) {
 			DelayCap: time.Hour,
 			DelayOut: time.Hour,
 		},
+		{
+			Name:     "Nth call that would overflow",
+			Count:    100,
+			Base:     2,
+			DelayIn:  time.Second,
+			DelayCap: time.Hour,
+			DelayOut: time.Hour,
+		},
 	}
 
 	for _, test := range tests {
@@ -551,3 +569,586 @@ func TestRun(t *testing.T) {
 		})
 	}
 }
+
+// TestRunCtxResult tests if a task can be ran by the retrier using a provided
+// context, and the result of the run reports the number of attempts
+func TestRunCtxResult(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Max      int
+		Delay    func(int) time.Duration
+		Task     func(ctx context.Context) (error, bool)
+		Attempts int
+		Elapsed  time.Duration
+		Error    error
+	}{
+		{
+			Name:  "Task succeeds immediately",
+			Max:   5,
+			Delay: ConstantDelay(time.Millisecond * 5),
+			Task: func(ctx context.Context) (error, bool) {
+				return nil, false
+			},
+			Attempts: 1,
+			Elapsed:  0,
+			Error:    nil,
+		},
+		{
+			Name:  "Task fails after max retries",
+			Max:   5,
+			Delay: ConstantDelay(time.Millisecond * 5),
+			Task: func(ctx context.Context) (error, bool) {
+				return fmt.Errorf("error"), true
+			},
+			Attempts: 6,
+			Elapsed:  time.Millisecond * 25,
+			Error:    fmt.Errorf("failed after max retries: error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(test.Max, test.Delay)
+
+			var res Result
+			var err error
+			ch := make(chan bool)
+			go func() {
+				res, err = retr.RunCtxResult(context.TODO(), test.Task)
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			if test.Error != nil {
+				assert.Error(t, err)
+				assert.EqualError(t, err, test.Error.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.Attempts, res.Attempts)
+			assert.GreaterOrEqual(t, res.Elapsed, test.Elapsed)
+		})
+	}
+}
+
+// TestRunResult tests if a task can be ran by the retrier, and the result of
+// the run reports the number of attempts
+func TestRunResult(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Max      int
+		Delay    func(int) time.Duration
+		Task     func() (error, bool)
+		Attempts int
+		Elapsed  time.Duration
+		Error    error
+	}{
+		{
+			Name:  "Task succeeds immediately",
+			Max:   5,
+			Delay: ConstantDelay(time.Millisecond * 5),
+			Task: func() (error, bool) {
+				return nil, false
+			},
+			Attempts: 1,
+			Elapsed:  0,
+			Error:    nil,
+		},
+		{
+			Name:  "Task fails after max retries",
+			Max:   5,
+			Delay: ConstantDelay(time.Millisecond * 5),
+			Task: func() (error, bool) {
+				return fmt.Errorf("error"), true
+			},
+			Attempts: 6,
+			Elapsed:  time.Millisecond * 25,
+			Error:    fmt.Errorf("failed after max retries: error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(test.Max, test.Delay)
+
+			var res Result
+			var err error
+			ch := make(chan bool)
+			go func() {
+				res, err = retr.RunResult(test.Task)
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			if test.Error != nil {
+				assert.Error(t, err)
+				assert.EqualError(t, err, test.Error.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.Attempts, res.Attempts)
+			assert.GreaterOrEqual(t, res.Elapsed, test.Elapsed)
+		})
+	}
+}
+
+// TestSleepReuse tests if timers reused by the sleep function do not fire
+// spuriously after a previous sleep was canceled
+func TestSleepReuse(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		ctx, cncl := context.WithCancel(context.TODO())
+		cncl()
+		sleep(ctx, time.Microsecond)
+	}
+
+	st := time.Now()
+	for i := 0; i < 100; i++ {
+		err := sleep(context.TODO(), time.Millisecond)
+		assert.NoError(t, err)
+	}
+	dif := time.Since(st)
+
+	assert.GreaterOrEqual(t, dif, time.Millisecond*100)
+}
+
+// BenchmarkSleepPooled measures the allocations of the sleep function, which
+// reuses timers from a pool
+func BenchmarkSleepPooled(b *testing.B) {
+	ctx := context.TODO()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sleep(ctx, 0)
+	}
+}
+
+// BenchmarkSleepTimeAfter measures the allocations of sleeping with a new
+// timer created by time.After for every call, for comparison
+func BenchmarkSleepTimeAfter(b *testing.B) {
+	ctx := context.TODO()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		select {
+		case <-time.After(0):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// TestFitsWithin tests if the retrier can estimate whether all of its attempts
+// fit within a deadline, and how many attempts fit if not
+func TestFitsWithin(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Max      int
+		Delay    func(int) time.Duration
+		Deadline time.Duration
+		Task     time.Duration
+		Fits     bool
+		Attempts int
+	}{
+		{
+			Name:     "All attempts fit",
+			Max:      3,
+			Delay:    ConstantDelay(time.Second),
+			Deadline: time.Second * 10,
+			Task:     time.Second,
+			Fits:     true,
+			Attempts: 4,
+		},
+		{
+			Name:     "All attempts fit exactly",
+			Max:      3,
+			Delay:    ConstantDelay(time.Second),
+			Deadline: time.Second * 7,
+			Task:     time.Second,
+			Fits:     true,
+			Attempts: 4,
+		},
+		{
+			Name:     "Some attempts fit",
+			Max:      5,
+			Delay:    LinearDelay(time.Second),
+			Deadline: time.Second * 9,
+			Task:     time.Second,
+			Fits:     false,
+			Attempts: 3,
+		},
+		{
+			Name:     "No attempts fit",
+			Max:      5,
+			Delay:    ConstantDelay(time.Second),
+			Deadline: time.Second,
+			Task:     time.Second * 2,
+			Fits:     false,
+			Attempts: 0,
+		},
+		{
+			Name:     "Unlimited retries",
+			Max:      -1,
+			Delay:    ConstantDelay(time.Second),
+			Deadline: time.Second * 10,
+			Task:     time.Second,
+			Fits:     false,
+			Attempts: 5,
+		},
+		{
+			Name:     "Unlimited retries without cost",
+			Max:      -1,
+			Delay:    NoDelay(),
+			Deadline: time.Second * 10,
+			Task:     0,
+			Fits:     false,
+			Attempts: -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(test.Max, test.Delay)
+			fits, attempts := retr.FitsWithin(test.Deadline, test.Task)
+
+			assert.Equal(t, test.Fits, fits)
+			assert.Equal(t, test.Attempts, attempts)
+		})
+	}
+}
+
+// TestFitsWithinDelays tests if estimating whether the attempts fit within a
+// deadline leaves the jittered delays of the next run unchanged
+func TestFitsWithinDelays(t *testing.T) {
+	run := func(estimate bool) []time.Duration {
+		delays := []time.Duration{}
+		retr := NewRetrier(
+			3,
+			ConstantDelay(time.Millisecond),
+			WithJitterAboveThreshold(0, 0.5),
+			WithRandSource(rand.NewSource(1)),
+			WithOnRetry(func(attempt int, err error, d time.Duration) {
+				delays = append(delays, d)
+			}),
+		)
+		if estimate {
+			fits, attempts := retr.FitsWithin(time.Second, time.Millisecond)
+			assert.True(t, fits)
+			assert.Equal(t, 4, attempts)
+		}
+		retr.Run(func() (error, bool) {
+			return errors.New("failed"), true
+		})
+		return delays
+	}
+
+	assert.Equal(t, run(false), run(true))
+}
+
+// TestZeroMax tests if Run and RunCtx execute a task exactly once without
+// retrying when retries are disabled, and return the error of the task as is
+func TestZeroMax(t *testing.T) {
+	tests := []struct {
+		Name string
+		Run  func(retr *Retrier, task func() (error, bool)) error
+	}{
+		{
+			Name: "Run",
+			Run: func(retr *Retrier, task func() (error, bool)) error {
+				return retr.Run(task)
+			},
+		},
+		{
+			Name: "RunCtx",
+			Run: func(retr *Retrier, task func() (error, bool)) error {
+				return retr.RunCtx(
+					context.TODO(),
+					func(ctx context.Context) (error, bool) {
+						return task()
+					},
+				)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(0, ConstantDelay(time.Millisecond*5))
+			taskErr := fmt.Errorf("error")
+
+			cnt := 0
+			err := test.Run(retr, func() (error, bool) {
+				cnt++
+				return taskErr, true
+			})
+
+			assert.Equal(t, 1, cnt)
+			assert.Same(t, taskErr, err)
+		})
+	}
+}
+
+// TestDelayFunc tests if delay functions can be capped, jittered and previewed
+// through the methods of the delay function type
+func TestDelayFunc(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Delay  DelayFunc
+		Count  int
+		Delays []time.Duration
+		Margin float64
+	}{
+		{
+			Name:  "Preview",
+			Delay: LinearDelay(time.Second),
+			Count: 4,
+			Delays: []time.Duration{
+				time.Second,
+				time.Second * 2,
+				time.Second * 3,
+				time.Second * 4,
+			},
+		},
+		{
+			Name:  "Capped preview",
+			Delay: ExponentialDelay(time.Second, 2).Capped(time.Second * 5),
+			Count: 4,
+			Delays: []time.Duration{
+				time.Second,
+				time.Second * 2,
+				time.Second * 4,
+				time.Second * 5,
+			},
+		},
+		{
+			Name:  "Jittered preview",
+			Delay: ConstantDelay(time.Second).Jittered(0.1),
+			Count: 3,
+			Delays: []time.Duration{
+				time.Second,
+				time.Second,
+				time.Second,
+			},
+			Margin: 0.1,
+		},
+		{
+			Name:   "Empty preview",
+			Delay:  NoDelay(),
+			Count:  0,
+			Delays: []time.Duration{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			delays := test.Delay.Preview(test.Count)
+
+			if assert.Len(t, delays, len(test.Delays)) {
+				for i := range delays {
+					assert.InDelta(
+						t,
+						float64(test.Delays[i]),
+						float64(delays[i]),
+						float64(test.Delays[i])*test.Margin,
+					)
+				}
+			}
+		})
+	}
+}
+
+// TestNewForeverRetrier tests if a retrier without a limit retries the task
+// until it decides not to retry
+func TestNewForeverRetrier(t *testing.T) {
+	retr := NewForeverRetrier(NoDelay())
+
+	count := 0
+	res, err := retr.RunResult(func() (error, bool) {
+		count++
+		return fmt.Errorf("error"), count < 100
+	})
+
+	assert.EqualError(t, err, "error")
+	assert.Equal(t, 100, res.Attempts)
+	assert.Equal(t, NoLimit, retr.max)
+}
+
+// TestCanceledBeforeStart tests if a task is not attempted when the context is
+// already canceled at the start of the run, and the last attempt is not
+// followed by a delay
+func TestCanceledBeforeStart(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Cancel   bool
+		Attempts int
+		Error    error
+	}{
+		{
+			Name:     "Canceled context",
+			Cancel:   true,
+			Attempts: 0,
+			Error:    context.Canceled,
+		},
+		{
+			Name:     "Active context",
+			Cancel:   false,
+			Attempts: 2,
+			Error:    ErrMaxRetriesExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(1, ConstantDelay(time.Millisecond*50))
+
+			ctx, cncl := context.WithCancel(context.TODO())
+			defer cncl()
+			if test.Cancel {
+				cncl()
+			}
+
+			attempts := 0
+			st := time.Now()
+			res, err := retr.RunCtxResult(ctx, func(ctx context.Context) (error, bool) {
+				attempts++
+				return fmt.Errorf("error"), true
+			})
+			dif := time.Since(st)
+
+			assert.ErrorIs(t, err, test.Error)
+			assert.Equal(t, test.Attempts, attempts)
+			assert.Equal(t, test.Attempts, res.Attempts)
+			assert.Less(t, dif, time.Millisecond*80)
+		})
+	}
+}
+
+// TestNewRetrierE tests if invalid configurations are rejected with a
+// descriptive error, and valid configurations create a retrier
+func TestNewRetrierE(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Max     int
+		Delayf  DelayFunc
+		Options []Option
+		Error   error
+	}{
+		{
+			Name:   "Valid configuration",
+			Max:    3,
+			Delayf: NoDelay(),
+			Error:  nil,
+		},
+		{
+			Name:   "Nil delay function",
+			Max:    3,
+			Delayf: nil,
+			Error:  fmt.Errorf("invalid retrier configuration: delay function is nil"),
+		},
+		{
+			Name:   "Max below limit",
+			Max:    -2,
+			Delayf: NoDelay(),
+			Error:  fmt.Errorf("invalid retrier configuration: max retries -2 is below NoLimit"),
+		},
+		{
+			Name:    "Max fixed by options",
+			Max:     -2,
+			Delayf:  NoDelay(),
+			Options: []Option{WithMaxAttempts(2)},
+			Error:   nil,
+		},
+		{
+			Name:   "Negative delay",
+			Max:    3,
+			Delayf: ConstantDelay(-time.Second),
+			Error:  fmt.Errorf("invalid retrier configuration: delay -1s is negative"),
+		},
+		{
+			Name:    "Negative attempt timeout",
+			Max:     3,
+			Delayf:  NoDelay(),
+			Options: []Option{WithAttemptTimeout(-time.Second)},
+			Error:   fmt.Errorf("invalid retrier configuration: attempt timeout -1s is negative"),
+		},
+		{
+			Name:    "Negative max elapsed time",
+			Max:     3,
+			Delayf:  NoDelay(),
+			Options: []Option{WithMaxElapsedTime(-time.Minute)},
+			Error:   fmt.Errorf("invalid retrier configuration: max elapsed time -1m0s is negative"),
+		},
+		{
+			Name:    "Negative cooldown",
+			Max:     3,
+			Delayf:  NoDelay(),
+			Options: []Option{WithCooldownFor(func(error) bool { return true }, -time.Second)},
+			Error:   fmt.Errorf("invalid retrier configuration: cooldown -1s is negative"),
+		},
+		{
+			Name:    "Jitter fraction above 1",
+			Max:     3,
+			Delayf:  NoDelay(),
+			Options: []Option{WithJitterAboveThreshold(0, 1.5)},
+			Error:   fmt.Errorf("invalid retrier configuration: jitter fraction 1.5 is not between 0 and 1"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr, err := NewRetrierE(test.Max, test.Delayf, test.Options...)
+			if test.Error != nil {
+				assert.EqualError(t, err, test.Error.Error())
+				assert.ErrorIs(t, err, ErrInvalidConfig)
+				assert.Nil(t, retr)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, retr)
+			}
+		})
+	}
+}
+
+// TestSafeDefaults tests if a nil delay function and negative delays do not
+// break the retrier
+func TestSafeDefaults(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Delayf DelayFunc
+	}{
+		{
+			Name:   "Nil delay function",
+			Delayf: nil,
+		},
+		{
+			Name:   "Negative delay",
+			Delayf: ConstantDelay(-time.Second),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			delays := []time.Duration{}
+			retr := NewRetrier(
+				2,
+				test.Delayf,
+				WithOnRetry(func(_ int, _ error, delay time.Duration) {
+					delays = append(delays, delay)
+				}),
+			)
+
+			err := retr.Run(func() (error, bool) {
+				return fmt.Errorf("error"), true
+			})
+
+			assert.ErrorIs(t, err, ErrMaxRetriesExceeded)
+			assert.Equal(t, []time.Duration{0, 0}, delays)
+		})
+	}
+}
@@ -0,0 +1,335 @@
+// Package retrieramqp keeps RabbitMQ connections of amqp091-go open with a
+// retrier, re-dialing and re-opening channels when they are closed.
+package retrieramqp
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Soreing/retrier"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrBufferFull is returned when a message is published while the manager is
+// reconnecting, and the buffer of messages waiting to be published is full.
+var ErrBufferFull = errors.New("publish buffer is full")
+
+// ErrClosed is returned when a message is published after the manager was
+// closed.
+var ErrClosed = errors.New("manager is closed")
+
+// ErrAlreadyConnected is returned when Connect is called while the manager is
+// connected or reconnecting.
+var ErrAlreadyConnected = errors.New("manager is already connected")
+
+// DefaultMaxBuffer is the default number of messages that are buffered while
+// the manager is reconnecting.
+const DefaultMaxBuffer = 1000
+
+// Channel is the interface of AMQP channels, which is implemented by
+// *amqp.Channel. It has the methods used to declare topology and to publish.
+type Channel interface {
+	ExchangeDeclare(
+		name, kind string,
+		durable, autoDelete, internal, noWait bool,
+		args amqp.Table,
+	) error
+	QueueDeclare(
+		name string,
+		durable, autoDelete, exclusive, noWait bool,
+		args amqp.Table,
+	) (amqp.Queue, error)
+	QueueBind(
+		name, key, exchange string,
+		noWait bool,
+		args amqp.Table,
+	) error
+	PublishWithContext(
+		ctx context.Context,
+		exchange, key string,
+		mandatory, immediate bool,
+		msg amqp.Publishing,
+	) error
+	NotifyClose(c chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+// Connection is the interface of AMQP connections that open channels.
+type Connection interface {
+	Channel() (Channel, error)
+	NotifyClose(c chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+// Dialer dials a connection.
+type Dialer func(ctx context.Context) (Connection, error)
+
+// connection adapts an *amqp.Connection to the Connection interface.
+type connection struct {
+	*amqp.Connection
+}
+
+// Channel opens a channel on the connection.
+func (c connection) Channel() (Channel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// Dial returns a dialer that dials the server at a URL with a config.
+func Dial(url string, cfg amqp.Config) Dialer {
+	return func(context.Context) (Connection, error) {
+		conn, err := amqp.DialConfig(url, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return connection{conn}, nil
+	}
+}
+
+// Topology declares the exchanges, queues and bindings used by a channel.
+type Topology func(ch Channel) error
+
+// message is a message waiting to be published.
+type message struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+// Manager keeps a connection and a channel open. When either of them is
+// closed by the server or by a network error, the manager re-dials the
+// connection and re-opens the channel with a retrier, and declares the
+// topology again. Messages published while reconnecting are buffered and
+// published after the channel is open again. It is safe for concurrent use.
+type Manager struct {
+	retr      *retrier.Retrier
+	dial      Dialer
+	topology  []Topology
+	maxBuffer int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	active bool
+	conn   Connection
+	ch     Channel
+	buffer []message
+	err    error
+}
+
+// Option configures a manager.
+type Option func(*Manager)
+
+// WithTopology adds a function that declares topology on the channel every
+// time it is opened.
+func WithTopology(topology Topology) Option {
+	return func(m *Manager) {
+		m.topology = append(m.topology, topology)
+	}
+}
+
+// WithMaxBuffer sets the number of messages that are buffered while
+// reconnecting, replacing DefaultMaxBuffer. Buffering is disabled when the
+// value is not positive.
+func WithMaxBuffer(n int) Option {
+	return func(m *Manager) {
+		m.maxBuffer = n
+	}
+}
+
+// NewManager creates a manager that dials connections with a dialer, and
+// reconnects with a retrier. The manager does not connect until Connect is
+// called.
+func NewManager(r *retrier.Retrier, dial Dialer, opts ...Option) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		retr:      r,
+		dial:      dial,
+		maxBuffer: DefaultMaxBuffer,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Connect dials a connection and opens a channel with the retrier, and keeps
+// them open until the manager is closed. If the retrier gives up, the error
+// of the last attempt is returned, and Connect can be called again. While the
+// manager is connected or reconnecting, ErrAlreadyConnected is returned.
+func (m *Manager) Connect(ctx context.Context) error {
+	m.mu.Lock()
+	if m.ctx.Err() != nil {
+		m.mu.Unlock()
+		return ErrClosed
+	} else if m.active {
+		m.mu.Unlock()
+		return ErrAlreadyConnected
+	}
+	m.active = true
+	m.err = nil
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-m.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := m.connect(ctx)
+	if err != nil {
+		m.mu.Lock()
+		m.active = false
+		m.mu.Unlock()
+	}
+	return err
+}
+
+// Publish publishes a message on the channel. If the manager is reconnecting,
+// the message is buffered and published after the channel is open again. If
+// the retrier gave up reconnecting, the error of reconnecting is returned.
+func (m *Manager) Publish(
+	ctx context.Context,
+	exchange string,
+	key string,
+	msg amqp.Publishing,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx.Err() != nil {
+		return ErrClosed
+	} else if m.err != nil {
+		return m.err
+	}
+
+	if m.ch != nil {
+		err := m.ch.PublishWithContext(ctx, exchange, key, false, false, msg)
+		if err == nil || !errors.Is(err, amqp.ErrClosed) {
+			return err
+		}
+	}
+
+	if len(m.buffer) >= m.maxBuffer {
+		return ErrBufferFull
+	}
+	m.buffer = append(m.buffer, message{exchange, key, msg})
+	return nil
+}
+
+// Close stops reconnecting, and closes the channel and the connection.
+// Buffered messages that were not published are dropped.
+func (m *Manager) Close() error {
+	m.cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.buffer = nil
+	if m.conn == nil {
+		return nil
+	}
+	m.ch.Close()
+	err := m.conn.Close()
+	m.conn, m.ch = nil, nil
+	return err
+}
+
+// connect dials a connection, opens a channel and declares the topology with
+// the retrier. When connected, buffered messages are published and the
+// connection is watched for closing.
+func (m *Manager) connect(ctx context.Context) error {
+	var conn Connection
+	var ch Channel
+	err := m.retr.RunCtxErr(ctx, func(ctx context.Context) error {
+		var err error
+		if conn, err = m.dial(ctx); err != nil {
+			return err
+		}
+		if ch, err = conn.Channel(); err != nil {
+			conn.Close()
+			return err
+		}
+		for _, topology := range m.topology {
+			if err := topology(ch); err != nil {
+				conn.Close()
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ctx.Err() != nil {
+		conn.Close()
+		return ErrClosed
+	}
+	m.conn, m.ch = conn, ch
+	m.flush()
+
+	go m.watch(connClosed, chClosed)
+	return nil
+}
+
+// flush publishes buffered messages until one of them fails. The caller must
+// hold the lock.
+func (m *Manager) flush() {
+	for len(m.buffer) > 0 {
+		msg := m.buffer[0]
+		err := m.ch.PublishWithContext(
+			m.ctx, msg.exchange, msg.key, false, false, msg.msg,
+		)
+		if err != nil {
+			return
+		}
+		m.buffer = m.buffer[1:]
+	}
+	m.buffer = nil
+}
+
+// watch waits for the connection or the channel to close, and reconnects
+// unless the manager is closed. If the retrier gives up reconnecting, the
+// error is kept and returned when publishing until Connect is called again.
+func (m *Manager) watch(connClosed, chClosed chan *amqp.Error) {
+	select {
+	case <-m.ctx.Done():
+		return
+	case <-connClosed:
+	case <-chClosed:
+	}
+
+	m.mu.Lock()
+	if m.ctx.Err() != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.conn.Close()
+	m.conn, m.ch = nil, nil
+	m.mu.Unlock()
+
+	if err := m.connect(m.ctx); err != nil && m.ctx.Err() == nil {
+		m.mu.Lock()
+		m.err = err
+		m.active = false
+		m.mu.Unlock()
+	}
+}
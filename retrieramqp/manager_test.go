@@ -0,0 +1,262 @@
+package retrieramqp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeChannel is a channel that records declared queues and published
+// messages, and can be closed to simulate a failure.
+type fakeChannel struct {
+	mu        sync.Mutex
+	queues    []string
+	published []string
+	closed    bool
+	notify    []chan *amqp.Error
+}
+
+func (c *fakeChannel) ExchangeDeclare(
+	name, kind string,
+	durable, autoDelete, internal, noWait bool,
+	args amqp.Table,
+) error {
+	return nil
+}
+
+func (c *fakeChannel) QueueDeclare(
+	name string,
+	durable, autoDelete, exclusive, noWait bool,
+	args amqp.Table,
+) (amqp.Queue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queues = append(c.queues, name)
+	return amqp.Queue{Name: name}, nil
+}
+
+func (c *fakeChannel) QueueBind(
+	name, key, exchange string,
+	noWait bool,
+	args amqp.Table,
+) error {
+	return nil
+}
+
+func (c *fakeChannel) PublishWithContext(
+	ctx context.Context,
+	exchange, key string,
+	mandatory, immediate bool,
+	msg amqp.Publishing,
+) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return amqp.ErrClosed
+	}
+	c.published = append(c.published, string(msg.Body))
+	return nil
+}
+
+func (c *fakeChannel) NotifyClose(ch chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = append(c.notify, ch)
+	return ch
+}
+
+func (c *fakeChannel) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+// fail closes the channel with an error, the way the server closes it.
+func (c *fakeChannel) fail() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, ch := range c.notify {
+		ch <- amqp.ErrClosed
+		close(ch)
+	}
+	c.notify = nil
+}
+
+// fakeConnection is a connection that opens one channel.
+type fakeConnection struct {
+	ch *fakeChannel
+}
+
+func (c *fakeConnection) Channel() (Channel, error) {
+	return c.ch, nil
+}
+
+func (c *fakeConnection) NotifyClose(ch chan *amqp.Error) chan *amqp.Error {
+	return ch
+}
+
+func (c *fakeConnection) Close() error {
+	return nil
+}
+
+// fakeDialer returns a dialer that fails a number of times before every
+// successful dial, and a function that returns the dialed channels.
+func fakeDialer(failures int) (Dialer, func() []*fakeChannel) {
+	var mu sync.Mutex
+	var chans []*fakeChannel
+	failed := 0
+	dial := func(ctx context.Context) (Connection, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failed < failures {
+			failed++
+			return nil, errors.New("connection refused")
+		}
+		failed = 0
+		ch := &fakeChannel{}
+		chans = append(chans, ch)
+		return &fakeConnection{ch: ch}, nil
+	}
+	dialed := func() []*fakeChannel {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*fakeChannel{}, chans...)
+	}
+	return dial, dialed
+}
+
+// declareQueue is a topology that declares a queue.
+func declareQueue(ch Channel) error {
+	_, err := ch.QueueDeclare("jobs", true, false, false, false, nil)
+	return err
+}
+
+// TestConnect tests if connecting retries failed dials, gives up with the
+// error of the last attempt when the retrier runs out of retries, and can only
+// be done again after giving up
+func TestConnect(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Failures int
+		Dialed   int
+		Err      string
+	}{
+		{
+			Name:     "Success",
+			Failures: 0,
+			Dialed:   1,
+			Err:      "",
+		},
+		{
+			Name:     "Retried dials",
+			Failures: 2,
+			Dialed:   1,
+			Err:      "",
+		},
+		{
+			Name:     "Retries run out",
+			Failures: 10,
+			Dialed:   0,
+			Err:      "failed after max retries: connection refused",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			dial, dialed := fakeDialer(test.Failures)
+			m := NewManager(
+				retrier.NewRetrier(2, retrier.NoDelay()),
+				dial,
+				WithTopology(declareQueue),
+			)
+			defer m.Close()
+
+			err := m.Connect(context.Background())
+			if test.Err != "" {
+				assert.EqualError(t, err, test.Err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			err = m.Connect(context.Background())
+			if test.Err != "" {
+				assert.EqualError(t, err, test.Err)
+			} else {
+				assert.ErrorIs(t, err, ErrAlreadyConnected)
+			}
+
+			chans := dialed()
+			assert.Len(t, chans, test.Dialed)
+			for _, ch := range chans {
+				assert.Equal(t, []string{"jobs"}, ch.queues)
+			}
+		})
+	}
+}
+
+// TestReconnect tests if the manager reconnects after the channel is closed,
+// declares the topology again, and publishes the messages buffered while
+// reconnecting
+func TestReconnect(t *testing.T) {
+	dial, dialed := fakeDialer(0)
+	m := NewManager(
+		retrier.NewRetrier(retrier.NoLimit, retrier.ConstantDelay(time.Millisecond*20)),
+		dial,
+		WithTopology(declareQueue),
+	)
+	defer m.Close()
+
+	assert.NoError(t, m.Connect(context.Background()))
+	ctx := context.Background()
+	assert.NoError(t, m.Publish(ctx, "", "jobs", amqp.Publishing{Body: []byte("a")}))
+
+	first := dialed()[0]
+	first.fail()
+	assert.NoError(t, m.Publish(ctx, "", "jobs", amqp.Publishing{Body: []byte("b")}))
+	assert.NoError(t, m.Publish(ctx, "", "jobs", amqp.Publishing{Body: []byte("c")}))
+
+	assert.Eventually(t, func() bool {
+		return len(dialed()) == 2
+	}, time.Second, time.Millisecond)
+
+	second := dialed()[1]
+	assert.Eventually(t, func() bool {
+		second.mu.Lock()
+		defer second.mu.Unlock()
+		return len(second.published) == 2
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, []string{"a"}, first.published)
+	assert.Equal(t, []string{"b", "c"}, second.published)
+	assert.Equal(t, []string{"jobs"}, second.queues)
+}
+
+// TestPublishBuffer tests if publishing while reconnecting fails when the
+// buffer is full, and after the manager is closed
+func TestPublishBuffer(t *testing.T) {
+	dial, dialed := fakeDialer(0)
+	m := NewManager(
+		retrier.NewRetrier(retrier.NoLimit, retrier.ConstantDelay(time.Hour)),
+		dial,
+		WithMaxBuffer(1),
+	)
+
+	assert.NoError(t, m.Connect(context.Background()))
+	dialed()[0].fail()
+
+	ctx := context.Background()
+	msg := amqp.Publishing{Body: []byte("a")}
+	assert.NoError(t, m.Publish(ctx, "", "jobs", msg))
+	assert.ErrorIs(t, m.Publish(ctx, "", "jobs", msg), ErrBufferFull)
+
+	m.Close()
+	assert.ErrorIs(t, m.Publish(ctx, "", "jobs", msg), ErrClosed)
+}
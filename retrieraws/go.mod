@@ -0,0 +1,18 @@
+module github.com/Soreing/retrier/retrieraws
+
+go 1.20
+
+require (
+	github.com/Soreing/retrier v0.0.0
+	github.com/aws/aws-sdk-go-v2 v1.21.0
+	github.com/aws/smithy-go v1.14.2
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/Soreing/retrier => ../
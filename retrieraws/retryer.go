@@ -0,0 +1,104 @@
+// Package retrieraws adapts retriers to the retryer interface of the AWS SDK
+// for Go v2, so SDK clients use the same delays and budgets as other tasks.
+package retrieraws
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// Retryer is an aws.Retryer that takes the max attempts, delays and retry
+// budget of SDK clients from a retrier. Errors are classified with the
+// retryable checks of the SDK unless a classifier is set.
+type Retryer struct {
+	retr     *retrier.Retrier
+	classify func(error) bool
+}
+
+// Option configures a retryer.
+type Option func(*Retryer)
+
+// WithClassifier sets the classifier that decides whether a failed attempt is
+// retried, replacing the default retryable checks of the SDK.
+func WithClassifier(classify func(err error) bool) Option {
+	return func(r *Retryer) {
+		r.classify = classify
+	}
+}
+
+// NewRetryer creates a retryer that is backed by a retrier. It is used by SDK
+// clients with config.WithRetryer or the Retryer field of client options.
+func NewRetryer(r *retrier.Retrier, opts ...Option) *Retryer {
+	rt := &Retryer{
+		retr:     r,
+		classify: Retryable,
+	}
+	for _, opt := range opts {
+		opt(rt)
+	}
+	return rt
+}
+
+// Retryable reports whether an error of an SDK operation is retryable with
+// the default retryable checks of the SDK, which recognize throttling,
+// transient status codes, timeouts and connection errors.
+func Retryable(err error) bool {
+	checks := retry.IsErrorRetryables(retry.DefaultRetryables)
+	return checks.IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// IsErrorRetryable reports whether a failed attempt is retried.
+func (r *Retryer) IsErrorRetryable(err error) bool {
+	return r.classify(err)
+}
+
+// MaxAttempts returns the number of attempts allowed by the retrier, which
+// is 0 when the retrier has no limit of retries.
+func (r *Retryer) MaxAttempts() int {
+	max := r.retr.Max()
+	if max == retrier.NoLimit {
+		return 0
+	}
+	return max + 1
+}
+
+// RetryDelay returns the delay of the retrier before the next attempt after
+// a failed attempt, starting from 1.
+func (r *Retryer) RetryDelay(attempt int, err error) (time.Duration, error) {
+	return r.retr.NextDelay(attempt-1, err), nil
+}
+
+// GetRetryToken takes a token from the retry budget of the retrier. If the
+// budget is exhausted, an *retrier.ExhaustedError with the reason
+// retrier.ErrBudgetExhausted is returned, which stops retrying.
+func (r *Retryer) GetRetryToken(
+	ctx context.Context,
+	err error,
+) (func(error) error, error) {
+	if b := r.retr.Budget(); b != nil && !b.Allow() {
+		return nil, &retrier.ExhaustedError{
+			Reason:  retrier.ErrBudgetExhausted,
+			LastErr: err,
+		}
+	}
+	return release, nil
+}
+
+// GetInitialToken returns a token for the first attempt, which has no cost.
+func (r *Retryer) GetInitialToken() func(error) error {
+	return release
+}
+
+// GetAttemptToken returns a token for an attempt, which has no cost.
+func (r *Retryer) GetAttemptToken(context.Context) (func(error) error, error) {
+	return release, nil
+}
+
+// release releases a token without a cost.
+func release(error) error {
+	return nil
+}
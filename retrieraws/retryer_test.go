@@ -0,0 +1,125 @@
+package retrieraws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+)
+
+// Retryer must be usable as the retryer of SDK clients.
+var _ aws.RetryerV2 = (*Retryer)(nil)
+
+// statusError returns an error of an SDK operation with a status code.
+func statusError(code int) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{
+				Response: &http.Response{StatusCode: code},
+			},
+			Err: errors.New("response error"),
+		},
+	}
+}
+
+// TestRetryable tests if transient status codes are retryable with the
+// default checks of the SDK, while client errors are not
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Err       error
+		Retryable bool
+	}{
+		{Name: "Service unavailable", Err: statusError(503), Retryable: true},
+		{Name: "Internal error", Err: statusError(500), Retryable: true},
+		{Name: "Not found", Err: statusError(404), Retryable: false},
+		{Name: "Other error", Err: errors.New("error"), Retryable: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Retryable, Retryable(test.Err))
+		})
+	}
+}
+
+// TestRetryer tests if the retryer takes the max attempts and delays from the
+// retrier, and classifies errors with the classifier
+func TestRetryer(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Retrier     *retrier.Retrier
+		Opts        []Option
+		Err         error
+		MaxAttempts int
+		Delays      []time.Duration
+		Retryable   bool
+	}{
+		{
+			Name:        "Limited retrier",
+			Retrier:     retrier.NewRetrier(3, retrier.LinearDelay(time.Second)),
+			Err:         statusError(503),
+			MaxAttempts: 4,
+			Delays:      []time.Duration{time.Second, time.Second * 2, time.Second * 3},
+			Retryable:   true,
+		},
+		{
+			Name:        "Unlimited retrier",
+			Retrier:     retrier.NewForeverRetrier(retrier.ConstantDelay(time.Second)),
+			Err:         statusError(404),
+			MaxAttempts: 0,
+			Delays:      []time.Duration{time.Second, time.Second},
+			Retryable:   false,
+		},
+		{
+			Name:    "Custom classifier",
+			Retrier: retrier.NewRetrier(1, retrier.NoDelay()),
+			Opts: []Option{WithClassifier(func(err error) bool {
+				return true
+			})},
+			Err:         statusError(404),
+			MaxAttempts: 2,
+			Delays:      []time.Duration{0},
+			Retryable:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			rt := NewRetryer(test.Retrier, test.Opts...)
+
+			assert.Equal(t, test.MaxAttempts, rt.MaxAttempts())
+			assert.Equal(t, test.Retryable, rt.IsErrorRetryable(test.Err))
+			for i, delay := range test.Delays {
+				dur, err := rt.RetryDelay(i+1, test.Err)
+				assert.NoError(t, err)
+				assert.Equal(t, delay, dur)
+			}
+		})
+	}
+}
+
+// TestRetryTokens tests if retry tokens are taken from the budget of the
+// retrier, and fail when the budget is exhausted
+func TestRetryTokens(t *testing.T) {
+	budget := retrier.NewBudget(2, time.Hour)
+	rt := NewRetryer(retrier.NewRetrier(5, retrier.NoDelay(), retrier.WithBudget(budget)))
+	errOp := statusError(503)
+
+	for i := 0; i < 2; i++ {
+		release, err := rt.GetRetryToken(context.Background(), errOp)
+		assert.NoError(t, err)
+		assert.NoError(t, release(nil))
+	}
+
+	_, err := rt.GetRetryToken(context.Background(), errOp)
+	assert.ErrorIs(t, err, retrier.ErrBudgetExhausted)
+	assert.ErrorIs(t, err, errOp)
+}
@@ -0,0 +1,20 @@
+module github.com/Soreing/retrier/retrierazure
+
+go 1.20
+
+require (
+	github.com/Azure/azure-sdk-for-go/sdk/azcore v1.7.2
+	github.com/Soreing/retrier v0.0.0
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/Azure/azure-sdk-for-go/sdk/internal v1.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.8.0 // indirect
+	golang.org/x/text v0.8.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/Soreing/retrier => ../
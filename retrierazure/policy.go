@@ -0,0 +1,172 @@
+// Package retrierazure provides an Azure SDK pipeline policy that retries
+// requests with a retrier, so Azure SDK clients and other tasks share the
+// same retry configuration.
+package retrierazure
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Soreing/retrier"
+	"github.com/Soreing/retrier/retrierhttp"
+)
+
+// DefaultStatusCodes are the status codes of responses that are retried by
+// default, which are the same as the built in retry policy of the SDK.
+var DefaultStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// nonRetriable is implemented by errors of the SDK that must not be retried.
+type nonRetriable interface {
+	error
+	NonRetriable()
+}
+
+// Policy is an Azure SDK pipeline policy that retries requests with a
+// retrier. Requests that fail with a transport error, or that receive a
+// response with a retryable status code are retried. When the retrier gives
+// up on a request that received a response, the last response is returned.
+//
+// The policy should be added to the per call policies of the client options,
+// and the built in retry policy should be disabled by setting MaxRetries of
+// the retry options to -1.
+type Policy struct {
+	retr     *retrier.Retrier
+	classify func(code int) bool
+	maxWait  time.Duration
+}
+
+// Option configures a policy.
+type Option func(*Policy)
+
+// WithStatusCodes sets the status codes of responses that are retried,
+// replacing DefaultStatusCodes.
+func WithStatusCodes(codes ...int) Option {
+	return func(p *Policy) {
+		p.classify = retrierhttp.StatusCodes(codes...)
+	}
+}
+
+// WithMaxRetryAfter caps the delay that a server can request with the headers
+// of a response, replacing retrierhttp.DefaultMaxRetryAfter. The cap is
+// disabled when the value is not positive.
+func WithMaxRetryAfter(d time.Duration) Option {
+	return func(p *Policy) {
+		p.maxWait = d
+	}
+}
+
+// NewPolicy creates a policy that retries requests with a retrier.
+func NewPolicy(r *retrier.Retrier, opts ...Option) *Policy {
+	p := &Policy{
+		retr:     r,
+		classify: retrierhttp.StatusCodes(DefaultStatusCodes...),
+		maxWait:  retrierhttp.DefaultMaxRetryAfter,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Do sends a request with the rest of the pipeline, and retries it according
+// to the retrier. The body of the request is rewound before every attempt.
+// Delays requested by the server with the headers recognized by
+// retrierhttp.RetryAfter are used before the next attempt, up to a cap.
+//
+// Each attempt sends the request with the context of the attempt, so its
+// values and timeout apply to the rest of the pipeline. The body of the
+// response may be read after the attempt has ended, so the timeout of the
+// attempt only applies until the response is received.
+func (p *Policy) Do(req *policy.Request) (*http.Response, error) {
+	// The built in retry policy closes the body after each attempt, so the
+	// body is only closed when the policy is done with it.
+	if body := req.Body(); body != nil {
+		ctype := req.Raw().Header.Get("Content-Type")
+		if err := req.SetBody(nopCloser{body}, ctype); err != nil {
+			return nil, err
+		}
+		defer body.Close()
+	}
+
+	ctx := req.Raw().Context()
+	var resp *http.Response
+	err := p.retr.RunCtx(ctx, func(actx context.Context) (error, bool) {
+		if resp != nil {
+			runtime.Drain(resp)
+			resp = nil
+		}
+		if err := req.RewindBody(); err != nil {
+			return err, false
+		}
+
+		// The response body may be read after the attempt has ended, so the
+		// request is sent with a context that is detached from the attempt
+		// once the response is received, and released when its body is
+		// closed.
+		sctx, detach, cancel := retrier.DetachAttempt(ctx, actx)
+		res, err := req.Clone(sctx).Next()
+		if err != nil {
+			cancel()
+			var nre nonRetriable
+			return err, !errors.As(err, &nre) && ctx.Err() == nil
+		}
+
+		detach()
+		res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+		resp = res
+		if p.classify(res.StatusCode) {
+			serr := &retrierhttp.StatusError{Code: res.StatusCode}
+			if wait, ok := retrierhttp.RetryAfter(res.Header, p.maxWait); ok {
+				return retrier.RetryAfter(serr, wait), true
+			}
+			return serr, true
+		}
+		return nil, false
+	})
+
+	var serr *retrierhttp.StatusError
+	if err == nil || (resp != nil && errors.As(err, &serr)) {
+		return resp, nil
+	}
+	if resp != nil {
+		runtime.Drain(resp)
+	}
+	return nil, err
+}
+
+// nopCloser is a request body that is not closed by the policies that send
+// it.
+type nopCloser struct {
+	io.ReadSeekCloser
+}
+
+// Close does nothing.
+func (nopCloser) Close() error {
+	return nil
+}
+
+// cancelBody is the body of a response that releases the context of its
+// request when it is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and releases the context of the request.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
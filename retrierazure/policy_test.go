@@ -0,0 +1,199 @@
+package retrierazure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+)
+
+// transporterFunc is a transport of the SDK that sends requests with a
+// function.
+type transporterFunc func(req *http.Request) (*http.Response, error)
+
+func (f transporterFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// nonRetriableError is an error that the SDK marks as not retriable.
+type nonRetriableError struct{}
+
+func (nonRetriableError) Error() string { return "non retriable" }
+
+func (nonRetriableError) NonRetriable() {}
+
+// TestPolicy tests if requests sent through a pipeline with the policy are
+// retried on transport errors and retryable status codes, with their body
+// sent again on every attempt
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Opts     []Option
+		Codes    []int
+		Errs     []error
+		Attempts int
+		Status   int
+		Err      error
+	}{
+		{
+			Name:     "Success",
+			Codes:    []int{200},
+			Attempts: 1,
+			Status:   200,
+		},
+		{
+			Name:     "Retryable status codes",
+			Codes:    []int{503, 429, 200},
+			Attempts: 3,
+			Status:   200,
+		},
+		{
+			Name:     "Retries run out",
+			Codes:    []int{500, 500, 500},
+			Attempts: 3,
+			Status:   500,
+		},
+		{
+			Name:     "Status code not retried",
+			Codes:    []int{404},
+			Attempts: 1,
+			Status:   404,
+		},
+		{
+			Name:     "Custom status codes",
+			Opts:     []Option{WithStatusCodes(404)},
+			Codes:    []int{404, 200},
+			Attempts: 2,
+			Status:   200,
+		},
+		{
+			Name:     "Transport error",
+			Codes:    []int{0, 200},
+			Errs:     []error{errors.New("connection reset"), nil},
+			Attempts: 2,
+			Status:   200,
+		},
+		{
+			Name:     "Non retriable error",
+			Codes:    []int{0},
+			Errs:     []error{nonRetriableError{}},
+			Attempts: 1,
+			Err:      nonRetriableError{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			attempts := 0
+			var bodies []string
+			transport := transporterFunc(func(req *http.Request) (*http.Response, error) {
+				body, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(body))
+				i := attempts
+				attempts++
+				if test.Errs != nil && test.Errs[i] != nil {
+					return nil, test.Errs[i]
+				}
+				return &http.Response{
+					StatusCode: test.Codes[i],
+					Header:     http.Header{"Retry-After-Ms": {"1"}},
+					Body:       io.NopCloser(bytes.NewReader(nil)),
+					Request:    req,
+				}, nil
+			})
+
+			retr := retrier.NewRetrier(2, retrier.NoDelay())
+			pl := runtime.NewPipeline("test", "v1.0.0", runtime.PipelineOptions{}, &policy.ClientOptions{
+				Transport:       transport,
+				Retry:           policy.RetryOptions{MaxRetries: -1},
+				PerCallPolicies: []policy.Policy{NewPolicy(retr, test.Opts...)},
+			})
+
+			req, err := runtime.NewRequest(context.Background(), http.MethodPut, "https://example.com/blob")
+			assert.NoError(t, err)
+			body := streaming.NopCloser(bytes.NewReader([]byte("data")))
+			assert.NoError(t, req.SetBody(body, "text/plain"))
+
+			resp, err := pl.Do(req)
+
+			if test.Err != nil {
+				assert.ErrorIs(t, err, test.Err)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.Status, resp.StatusCode)
+			}
+			assert.Equal(t, test.Attempts, attempts)
+			for _, body := range bodies {
+				assert.Equal(t, "data", body)
+			}
+		})
+	}
+}
+
+// TestPolicyAttemptContext tests if requests are sent with the context of the
+// attempt, and if server requested delays are capped
+func TestPolicyAttemptContext(t *testing.T) {
+	numbers := []int{}
+	transport := transporterFunc(func(req *http.Request) (*http.Response, error) {
+		att, _ := retrier.AttemptFromContext(req.Context())
+		numbers = append(numbers, att.Number)
+		code := 503
+		if att.Number == 2 {
+			code = 200
+		}
+		return &http.Response{
+			StatusCode: code,
+			Header:     http.Header{"Retry-After": {"3600"}},
+			Body:       io.NopCloser(bytes.NewReader(nil)),
+			Request:    req,
+		}, nil
+	})
+
+	delays := []time.Duration{}
+	retr := retrier.NewRetrier(2, retrier.NoDelay(),
+		retrier.WithAttemptTimeout(time.Second),
+		retrier.WithClock(instantClock{}),
+		retrier.WithOnRetry(func(_ int, _ error, delay time.Duration) {
+			delays = append(delays, delay)
+		}),
+	)
+	pl := runtime.NewPipeline("test", "v1.0.0", runtime.PipelineOptions{}, &policy.ClientOptions{
+		Transport:       transport,
+		Retry:           policy.RetryOptions{MaxRetries: -1},
+		PerCallPolicies: []policy.Policy{NewPolicy(retr, WithMaxRetryAfter(time.Minute))},
+	})
+
+	req, err := runtime.NewRequest(context.Background(), http.MethodGet, "https://example.com/blob")
+	assert.NoError(t, err)
+	resp, err := pl.Do(req)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, 200, resp.StatusCode)
+		resp.Body.Close()
+	}
+	assert.Equal(t, []int{1, 2}, numbers)
+	assert.Equal(t, []time.Duration{time.Minute}, delays)
+}
+
+// instantClock is a clock whose timers fire immediately.
+type instantClock struct{}
+
+func (instantClock) Now() time.Time {
+	return time.Now()
+}
+
+func (instantClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
@@ -0,0 +1,186 @@
+// Package retrierelastic retries Elasticsearch bulk requests with a retrier,
+// sending again only the items that failed with transient errors.
+package retrierelastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Soreing/retrier"
+	"github.com/Soreing/retrier/retrierhttp"
+)
+
+// Client is the interface of the clients that send requests to a cluster,
+// which is implemented by the clients of go-elasticsearch.
+type Client interface {
+	Perform(req *http.Request) (*http.Response, error)
+}
+
+// ClientFunc is a function that sends requests to a cluster.
+type ClientFunc func(req *http.Request) (*http.Response, error)
+
+// Perform sends a request with the function.
+func (f ClientFunc) Perform(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Item is an operation of a bulk request.
+type Item struct {
+	// Action is the action and metadata line of the operation, such as
+	// {"index":{"_index":"logs","_id":"1"}}.
+	Action []byte
+
+	// Source is the document line of the operation, which is nil for delete
+	// operations.
+	Source []byte
+}
+
+// ItemResult is the result of an operation of a bulk request.
+type ItemResult struct {
+	// Status is the status code of the operation, or 0 if the operation was
+	// never sent.
+	Status int
+
+	// Error is the error of the operation as returned by the cluster, or nil
+	// if the operation succeeded.
+	Error json.RawMessage
+}
+
+// Failed reports whether the operation failed.
+func (r ItemResult) Failed() bool {
+	return r.Status < 200 || r.Status > 299
+}
+
+// bulkResponse is the body of the response of a bulk request.
+type bulkResponse struct {
+	Errors bool                         `json:"errors"`
+	Items  []map[string]json.RawMessage `json:"items"`
+}
+
+// itemResponse is the result of an operation in the response of a bulk
+// request.
+type itemResponse struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// Retryable reports whether an operation of a bulk request that failed with a
+// status code can be sent again. Operations that were rejected because the
+// cluster is overloaded or unavailable are retryable.
+func Retryable(status int) bool {
+	return status == http.StatusTooManyRequests ||
+		status == http.StatusServiceUnavailable
+}
+
+// Bulk sends the operations of a bulk request to the bulk endpoint at a URL,
+// and retries the operations that were rejected with retryable status codes
+// according to the retrier. Operations that succeeded are not sent again,
+// and operations that failed with other errors, such as mapping errors, are
+// not retried. Bulk requests that fail entirely with a retryable status code,
+// a connection error as classified by retrierhttp.Retryable, or an attempt
+// timeout of the retrier are retried as a whole.
+//
+// The results of the operations are returned in order. The error is nil when
+// every operation succeeded or failed permanently, which can be told apart by
+// the results. When the retrier gives up on operations, the results of the
+// last attempt are returned along with the error. Without operations, no
+// request is sent.
+func Bulk(
+	ctx context.Context,
+	r *retrier.Retrier,
+	c Client,
+	url string,
+	items []Item,
+) ([]ItemResult, error) {
+	results := make([]ItemResult, len(items))
+	if len(items) == 0 {
+		return results, nil
+	}
+
+	pending := make([]int, len(items))
+	for i := range pending {
+		pending[i] = i
+	}
+	retryCode := retrierhttp.StatusCodes(retrierhttp.DefaultStatusCodes...)
+
+	err := r.RunCtx(ctx, func(actx context.Context) (error, bool) {
+		resp, err := send(actx, c, url, items, pending)
+		if err != nil {
+			timedOut := actx.Err() != nil
+			return err, ctx.Err() == nil &&
+				(timedOut || retrierhttp.Retryable(err))
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			return &retrierhttp.StatusError{Code: resp.StatusCode},
+				retryCode(resp.StatusCode)
+		}
+
+		var body bulkResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return err, false
+		} else if len(body.Items) != len(pending) {
+			return fmt.Errorf(
+				"bulk response has %d items for %d operations",
+				len(body.Items), len(pending),
+			), false
+		}
+
+		var failed []int
+		for i, item := range body.Items {
+			var res itemResponse
+			for _, raw := range item {
+				if err := json.Unmarshal(raw, &res); err != nil {
+					return err, false
+				}
+			}
+
+			idx := pending[i]
+			results[idx] = ItemResult{Status: res.Status, Error: res.Error}
+			if Retryable(res.Status) {
+				failed = append(failed, idx)
+			}
+		}
+
+		pending = failed
+		if len(failed) > 0 {
+			return fmt.Errorf(
+				"%d bulk operations were rejected", len(failed),
+			), true
+		}
+		return nil, false
+	})
+	return results, err
+}
+
+// send sends a bulk request with the pending operations.
+func send(
+	ctx context.Context,
+	c Client,
+	url string,
+	items []Item,
+	pending []int,
+) (*http.Response, error) {
+	buf := bytes.Buffer{}
+	for _, idx := range pending {
+		buf.Write(items[idx].Action)
+		buf.WriteByte('\n')
+		if items[idx].Source != nil {
+			buf.Write(items[idx].Source)
+			buf.WriteByte('\n')
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &buf)
+	if err != nil {
+		return nil, retrier.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	return c.Perform(req)
+}
@@ -0,0 +1,238 @@
+package retrierelastic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/Soreing/retrier/retrierhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCluster returns a client that answers bulk requests with the status
+// codes of a list of attempts for every document ID, and records the IDs
+// sent in every attempt.
+func fakeCluster(
+	statuses map[string][]int,
+	requests *[][]string,
+) ClientFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		var ids []string
+		var items []map[string]itemResponse
+		sc := bufio.NewScanner(req.Body)
+		for sc.Scan() {
+			var action map[string]struct {
+				ID string `json:"_id"`
+			}
+			json.Unmarshal(sc.Bytes(), &action)
+			id := action["index"].ID
+			sc.Scan()
+
+			status := 201
+			if codes := statuses[id]; len(codes) > 0 {
+				status, statuses[id] = codes[0], codes[1:]
+			}
+			res := itemResponse{Status: status}
+			if status != 201 {
+				res.Error = json.RawMessage(`{"type":"error"}`)
+			}
+			ids = append(ids, id)
+			items = append(items, map[string]itemResponse{"index": res})
+		}
+		*requests = append(*requests, ids)
+
+		body, _ := json.Marshal(map[string]any{"errors": true, "items": items})
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+		}, nil
+	}
+}
+
+// indexItem returns an index operation for a document ID.
+func indexItem(id string) Item {
+	return Item{
+		Action: []byte(`{"index":{"_index":"logs","_id":"` + id + `"}}`),
+		Source: []byte(`{"message":"` + id + `"}`),
+	}
+}
+
+// TestBulk tests if only the operations that were rejected with retryable
+// status codes are sent again, while permanent failures are passed through
+func TestBulk(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Statuses map[string][]int
+		Requests [][]string
+		Results  []int
+		Err      error
+	}{
+		{
+			Name:     "Success",
+			Statuses: map[string][]int{},
+			Requests: [][]string{{"1", "2", "3"}},
+			Results:  []int{201, 201, 201},
+		},
+		{
+			Name:     "Rejected operations retried",
+			Statuses: map[string][]int{"1": {429}, "3": {503, 429}},
+			Requests: [][]string{{"1", "2", "3"}, {"1", "3"}, {"3"}},
+			Results:  []int{201, 201, 201},
+		},
+		{
+			Name:     "Mapping error passed through",
+			Statuses: map[string][]int{"2": {400}, "3": {429}},
+			Requests: [][]string{{"1", "2", "3"}, {"3"}},
+			Results:  []int{201, 400, 201},
+		},
+		{
+			Name:     "Retries run out",
+			Statuses: map[string][]int{"2": {429, 429, 429}},
+			Requests: [][]string{{"1", "2", "3"}, {"2"}, {"2"}},
+			Results:  []int{201, 429, 201},
+			Err:      retrier.ErrMaxRetriesExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var requests [][]string
+			c := fakeCluster(test.Statuses, &requests)
+			items := []Item{indexItem("1"), indexItem("2"), indexItem("3")}
+
+			retr := retrier.NewRetrier(2, retrier.NoDelay())
+			res, err := Bulk(context.Background(), retr, c, "http://localhost:9200/_bulk", items)
+
+			if test.Err != nil {
+				assert.ErrorIs(t, err, test.Err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.Requests, requests)
+			for i, status := range test.Results {
+				assert.Equal(t, status, res[i].Status)
+				assert.Equal(t, status != 201, res[i].Failed())
+			}
+		})
+	}
+}
+
+// TestBulkRequestErrors tests if bulk requests that fail entirely are
+// retried as a whole when the failure is transient, and returned otherwise
+func TestBulkRequestErrors(t *testing.T) {
+	errInvalid := errors.New("invalid url")
+
+	tests := []struct {
+		Name     string
+		Statuses []int
+		Errs     []error
+		Attempts int
+		Err      error
+	}{
+		{
+			Name:     "Unavailable cluster",
+			Statuses: []int{503, 502, 200},
+			Attempts: 3,
+		},
+		{
+			Name:     "Connection error",
+			Statuses: []int{0, 200},
+			Errs:     []error{syscall.ECONNREFUSED, nil},
+			Attempts: 2,
+		},
+		{
+			Name:     "Bad request",
+			Statuses: []int{400},
+			Attempts: 1,
+			Err:      &retrierhttp.StatusError{Code: 400},
+		},
+		{
+			Name:     "Fatal error",
+			Statuses: []int{0},
+			Errs:     []error{errInvalid},
+			Attempts: 1,
+			Err:      errInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			attempts := 0
+			c := ClientFunc(func(req *http.Request) (*http.Response, error) {
+				i := attempts
+				attempts++
+				if test.Errs != nil && test.Errs[i] != nil {
+					return nil, test.Errs[i]
+				}
+				body := `{"errors":false,"items":[{"index":{"status":201}}]}`
+				return &http.Response{
+					StatusCode: test.Statuses[i],
+					Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+				}, nil
+			})
+
+			retr := retrier.NewRetrier(3, retrier.NoDelay())
+			res, err := Bulk(context.Background(), retr, c, "http://localhost:9200/_bulk", []Item{indexItem("1")})
+
+			if test.Err != nil {
+				assert.Equal(t, test.Err, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 201, res[0].Status)
+			}
+			assert.Equal(t, test.Attempts, attempts)
+		})
+	}
+}
+
+// TestBulkEmpty tests if no request is sent for a bulk request without
+// operations
+func TestBulkEmpty(t *testing.T) {
+	requests := 0
+	c := ClientFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return nil, errors.New("connection refused")
+	})
+
+	retr := retrier.NewRetrier(3, retrier.NoDelay())
+	res, err := Bulk(context.Background(), retr, c, "http://localhost:9200/_bulk", nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+	assert.Equal(t, 0, requests)
+}
+
+// TestBulkAttemptTimeout tests if bulk requests that are cut off by the
+// attempt timeout of the retrier are retried
+func TestBulkAttemptTimeout(t *testing.T) {
+	attempts := 0
+	c := ClientFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts < 3 {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		body := `{"errors":false,"items":[{"index":{"status":201}}]}`
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+		}, nil
+	})
+
+	retr := retrier.NewRetrier(3, retrier.NoDelay(),
+		retrier.WithAttemptTimeout(time.Millisecond*10),
+	)
+	res, err := Bulk(context.Background(), retr, c, "http://localhost:9200/_bulk", []Item{indexItem("1")})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 201, res[0].Status)
+	assert.Equal(t, 3, attempts)
+}
@@ -0,0 +1,71 @@
+// Package retrierexpvar publishes counters of retriers with expvar, so they
+// are served at /debug/vars with the other variables of the process.
+package retrierexpvar
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/Soreing/retrier"
+)
+
+// Vars are the counters of a retrier published as an expvar map. They are
+// added to a retrier as an observer with retrier.WithObserver, and are safe
+// for concurrent use.
+//
+// The published counters are:
+//   - attempts counts the attempts of tasks.
+//   - successes counts the runs where the task succeeded.
+//   - exhaustions counts the runs that the retrier gave up on.
+//   - sleep_seconds is the total delay before retries in seconds.
+type Vars struct {
+	attempts    *expvar.Int
+	successes   *expvar.Int
+	exhaustions *expvar.Int
+	sleep       *expvar.Float
+}
+
+// Publish creates the counters of a retrier and publishes them as an expvar
+// map with a name. Like expvar.Publish, it panics if the name is already in
+// use, so every retrier must be published with a different name.
+func Publish(name string) *Vars {
+	v := &Vars{
+		attempts:    new(expvar.Int),
+		successes:   new(expvar.Int),
+		exhaustions: new(expvar.Int),
+		sleep:       new(expvar.Float),
+	}
+
+	m := new(expvar.Map)
+	m.Set("attempts", v.attempts)
+	m.Set("successes", v.successes)
+	m.Set("exhaustions", v.exhaustions)
+	m.Set("sleep_seconds", v.sleep)
+	expvar.Publish(name, m)
+	return v
+}
+
+// ObserveAttempt counts an attempt of a task.
+func (v *Vars) ObserveAttempt(name string, rec retrier.AttemptRecord) {
+	v.attempts.Add(1)
+}
+
+// ObserveRetry adds the delay before a retry to the total delay.
+func (v *Vars) ObserveRetry(
+	name string,
+	attempt int,
+	err error,
+	delay time.Duration,
+) {
+	v.sleep.Add(delay.Seconds())
+}
+
+// ObserveRun counts the runs where the task succeeded, and the runs that the
+// retrier gave up on.
+func (v *Vars) ObserveRun(name string, res retrier.Result, err error) {
+	if err == nil {
+		v.successes.Add(1)
+	} else if _, ok := err.(*retrier.ExhaustedError); ok {
+		v.exhaustions.Add(1)
+	}
+}
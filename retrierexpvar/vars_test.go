@@ -0,0 +1,54 @@
+package retrierexpvar
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+)
+
+// published counts the variables published by the tests, so that every run of
+// a test publishes its variables under a new name.
+var published atomic.Int64
+
+// varName returns a name of a variable that is unique to a run of a test.
+func varName(t *testing.T) string {
+	return fmt.Sprintf("%s_%d", t.Name(), published.Add(1))
+}
+
+// TestPublish tests if the attempts, successes, exhaustions and the total
+// delay of a retrier are published under the name of the counters
+func TestPublish(t *testing.T) {
+	name := varName(t)
+	retr := retrier.NewRetrier(2, retrier.ConstantDelay(time.Millisecond*5),
+		retrier.WithObserver(Publish(name)),
+	)
+
+	retr.Run(func() (error, bool) {
+		return fmt.Errorf("error"), true
+	})
+	count := 0
+	retr.Run(func() (error, bool) {
+		count++
+		return nil, count < 2
+	})
+	retr.Run(func() (error, bool) {
+		return fmt.Errorf("error"), false
+	})
+
+	vars := map[string]float64{}
+	err := json.Unmarshal([]byte(expvar.Get(name).String()), &vars)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{
+		"attempts":      6,
+		"successes":     1,
+		"exhaustions":   1,
+		"sleep_seconds": 0.015,
+	}, vars)
+	assert.Panics(t, func() { Publish(name) })
+}
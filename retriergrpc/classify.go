@@ -0,0 +1,62 @@
+package retriergrpc
+
+import (
+	"time"
+
+	"github.com/Soreing/retrier"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Classifier decides whether errors of gRPC calls are retried by their status
+// codes. Codes that are not in the classifier are not retried.
+type Classifier map[codes.Code]bool
+
+// Codes creates a classifier that retries errors with any of the codes.
+func Codes(cs ...codes.Code) Classifier {
+	c := make(Classifier, len(cs))
+	for _, code := range cs {
+		c[code] = true
+	}
+	return c
+}
+
+// DefaultClassifier retries errors with codes.Unavailable, which is the only
+// code that is always safe to retry.
+var DefaultClassifier = Codes(codes.Unavailable)
+
+// Retryable reports whether an error is retried by its status code. It can be
+// used as the classifier of a retrier with retrier.WithRetryIf.
+func (c Classifier) Retryable(err error) bool {
+	return err != nil && c[status.Code(err)]
+}
+
+// Classify decides whether an error of a gRPC call is retried, and returns it
+// in the form that a task returns to the retrier. If the status of the error
+// has RetryInfo details, the error is wrapped with the delay that the server
+// requests, so it overrides the delay function of the retrier.
+func (c Classifier) Classify(err error) (error, bool) {
+	if err == nil {
+		return nil, false
+	}
+	if delay, ok := RetryDelay(err); ok {
+		return retrier.RetryAfter(err, delay), c.Retryable(err)
+	}
+	return err, c.Retryable(err)
+}
+
+// RetryDelay returns the delay that the server requests before retrying from
+// the RetryInfo details of the status of an error.
+func RetryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay != nil {
+			return info.RetryDelay.AsDuration(), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,111 @@
+package retriergrpc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// TestClassifier tests if errors are retried by their status codes, and the
+// delay of RetryInfo details overrides the delay function of the retrier
+func TestClassifier(t *testing.T) {
+	withRetryInfo := func(code codes.Code, delay time.Duration) error {
+		st, _ := status.New(code, "retry later").WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(delay),
+		})
+		return st.Err()
+	}
+
+	tests := []struct {
+		Name       string
+		Classifier Classifier
+		Error      error
+		Attempts   int
+		Delay      time.Duration
+	}{
+		{
+			Name:       "Default code is retried",
+			Classifier: DefaultClassifier,
+			Error:      status.Error(codes.Unavailable, "unavailable"),
+			Attempts:   3,
+			Delay:      time.Millisecond,
+		},
+		{
+			Name:       "Other code is not retried",
+			Classifier: DefaultClassifier,
+			Error:      status.Error(codes.InvalidArgument, "invalid"),
+			Attempts:   1,
+		},
+		{
+			Name:       "Custom code is retried",
+			Classifier: Codes(codes.ResourceExhausted),
+			Error:      status.Error(codes.ResourceExhausted, "exhausted"),
+			Attempts:   3,
+			Delay:      time.Millisecond,
+		},
+		{
+			Name:       "Non-status error is not retried",
+			Classifier: DefaultClassifier,
+			Error:      fmt.Errorf("error"),
+			Attempts:   1,
+		},
+		{
+			Name:       "Retry info overrides delay",
+			Classifier: Codes(codes.ResourceExhausted),
+			Error:      withRetryInfo(codes.ResourceExhausted, time.Millisecond*20),
+			Attempts:   3,
+			Delay:      time.Millisecond * 20,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			delays := []time.Duration{}
+			retr := retrier.NewRetrier(
+				2,
+				retrier.ConstantDelay(time.Millisecond),
+				retrier.WithOnRetry(func(_ int, _ error, delay time.Duration) {
+					delays = append(delays, delay)
+				}),
+			)
+
+			attempts := 0
+			err := retr.RunCtx(context.TODO(), func(ctx context.Context) (error, bool) {
+				attempts++
+				return test.Classifier.Classify(test.Error)
+			})
+
+			assert.Equal(t, status.Code(test.Error), status.Code(err))
+			assert.Equal(t, test.Attempts, attempts)
+			for _, delay := range delays {
+				assert.Equal(t, test.Delay, delay)
+			}
+		})
+	}
+}
+
+// TestRetryable tests if the classifier can be used as the classifier of a
+// retrier
+func TestRetryable(t *testing.T) {
+	retr := retrier.NewRetrier(
+		2,
+		retrier.NoDelay(),
+		retrier.WithRetryIf(DefaultClassifier.Retryable),
+	)
+
+	attempts := 0
+	retr.Run(func() (error, bool) {
+		attempts++
+		return status.Error(codes.Unavailable, "unavailable"), false
+	})
+
+	assert.Equal(t, 3, attempts)
+}
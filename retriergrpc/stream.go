@@ -0,0 +1,213 @@
+// Package retriergrpc retries gRPC calls with a retrier through client
+// interceptors.
+package retriergrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/Soreing/retrier"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// StreamOption configures a stream client interceptor.
+type StreamOption func(*streamConfig)
+
+// streamConfig is the configuration of a stream client interceptor.
+type streamConfig struct {
+	resume   func(req any) (any, error)
+	classify Classifier
+}
+
+// WithClassifier sets the classifier that decides which errors are retried
+// when opening and re-establishing streams. The default is DefaultClassifier.
+func WithClassifier(c Classifier) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.classify = c
+	}
+}
+
+// WithResume sets a callback that recomputes the request of a server
+// streaming RPC from the previous request before it is re-established, for
+// example to continue from a resume token instead of the beginning.
+func WithResume(resume func(req any) (any, error)) StreamOption {
+	return func(c *streamConfig) {
+		c.resume = resume
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that retries opening streams
+// that fail with a retryable error, and transparently re-establishes server
+// streaming RPCs that fail with a retryable error while receiving, by opening
+// a new stream and sending the request again. Errors with codes.Unavailable
+// are retried unless a classifier is set. Client and bidirectional streaming
+// RPCs are only retried while opening the stream. When the retrier gives up,
+// the status error of the last attempt is returned.
+//
+// Streams are opened with the context of the attempt, so its values reach
+// the stream, but they are detached from the attempt once they are open,
+// because they are used after the attempt has ended.
+func StreamClientInterceptor(
+	r *retrier.Retrier,
+	opts ...StreamOption,
+) grpc.StreamClientInterceptor {
+	cfg := &streamConfig{classify: DefaultClassifier}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		callOpts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		dial := func(actx context.Context) (*stream, error) {
+			sctx, detach, cancel := retrier.DetachAttempt(ctx, actx)
+			cs, err := streamer(sctx, desc, cc, method, callOpts...)
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			detach()
+			return &stream{
+				ClientStream: cs,
+				cancel:       cancel,
+				single:       !desc.ServerStreams,
+			}, nil
+		}
+
+		var st *stream
+		var last error
+		err := r.RunCtx(ctx, func(actx context.Context) (error, bool) {
+			var err error
+			st, err = dial(actx)
+			last = err
+			return cfg.classify.Classify(err)
+		})
+		if err != nil {
+			return nil, statusError(err, last)
+		}
+		if !desc.ServerStreams || desc.ClientStreams {
+			return st, nil
+		}
+		return &resumableStream{
+			stream:   st,
+			ctx:      ctx,
+			retr:     r,
+			dial:     dial,
+			resume:   cfg.resume,
+			classify: cfg.classify,
+		}, nil
+	}
+}
+
+// stream is a client stream that releases its context when it ends.
+type stream struct {
+	grpc.ClientStream
+	cancel context.CancelFunc
+	single bool
+}
+
+// RecvMsg receives a message from the stream, and releases the context of
+// the stream when it ends. Streams without server streaming end after their
+// only message is received, such as with CloseAndRecv.
+func (s *stream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || s.single {
+		s.cancel()
+	}
+	return err
+}
+
+// resumableStream is a server stream that is re-established when it fails
+// with codes.Unavailable while receiving.
+type resumableStream struct {
+	*stream
+	ctx      context.Context
+	retr     *retrier.Retrier
+	dial     func(actx context.Context) (*stream, error)
+	resume   func(req any) (any, error)
+	classify Classifier
+	req      any
+	sent     bool
+}
+
+// SendMsg sends the request of the stream and keeps it to send it again when
+// the stream is re-established.
+func (s *resumableStream) SendMsg(m any) error {
+	s.req = m
+	s.sent = true
+	return s.stream.SendMsg(m)
+}
+
+// RecvMsg receives a message from the stream. If the stream fails with
+// codes.Unavailable, a new stream is opened with the request, and the message
+// is received from the new stream. Opening the stream, sending the request
+// and receiving the message are retried together in a single run.
+func (s *resumableStream) RecvMsg(m any) error {
+	err := s.stream.RecvMsg(m)
+	if !s.sent || !s.classify.Retryable(err) {
+		return err
+	}
+
+	var last error
+	err = s.retr.RunCtx(s.ctx, func(actx context.Context) (error, bool) {
+		req := s.req
+		if s.resume != nil {
+			var err error
+			if req, err = s.resume(req); err != nil {
+				last = err
+				return err, false
+			}
+		}
+
+		st, err := s.dial(actx)
+		if err != nil {
+			last = err
+			return s.classify.Classify(err)
+		}
+		s.stream = st
+		if err := st.SendMsg(req); err != nil && err != io.EOF {
+			st.cancel()
+			last = err
+			return s.classify.Classify(err)
+		}
+		if err := st.CloseSend(); err != nil {
+			st.cancel()
+			last = err
+			return s.classify.Classify(err)
+		}
+
+		s.req = req
+		err = st.RecvMsg(m)
+		last = err
+		if err == io.EOF {
+			return err, false
+		}
+		return s.classify.Classify(err)
+	})
+	return statusError(err, last)
+}
+
+// statusError converts the error of a run to the error that is returned to
+// the callers of gRPC, who match errors by their status code. If the retrier
+// gave up, the error of the last attempt is returned instead of the
+// *retrier.ExhaustedError. Errors without a status are converted with
+// status.FromContextError, except for io.EOF which ends streams.
+func statusError(err error, last error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if last != nil && errors.Is(err, last) {
+		err = last
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.FromContextError(err).Err()
+}
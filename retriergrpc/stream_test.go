@@ -0,0 +1,274 @@
+package retriergrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeStream is a client stream that receives a script of messages, and then
+// fails with an error.
+type fakeStream struct {
+	msgs []string
+	err  error
+	reqs *[]string
+}
+
+func (s *fakeStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *fakeStream) Trailer() metadata.MD         { return nil }
+func (s *fakeStream) CloseSend() error             { return nil }
+func (s *fakeStream) Context() context.Context     { return context.TODO() }
+
+func (s *fakeStream) SendMsg(m any) error {
+	*s.reqs = append(*s.reqs, *m.(*string))
+	return nil
+}
+
+func (s *fakeStream) RecvMsg(m any) error {
+	if len(s.msgs) == 0 {
+		return s.err
+	}
+	*m.(*string) = s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return nil
+}
+
+// fakeStreamer returns a streamer that opens streams from a list of scripts,
+// where a stream without messages and with an error fails to open.
+func fakeStreamer(streams []*fakeStream, opened *int) grpc.Streamer {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		s := streams[*opened]
+		*opened++
+		if len(s.msgs) == 0 && s.err != io.EOF {
+			return nil, s.err
+		}
+		return s, nil
+	}
+}
+
+// TestStreamClientInterceptor tests if server streams are re-established when
+// they fail with codes.Unavailable, with the request recomputed by the resume
+// callback in a single run, while other errors are returned as status errors
+func TestStreamClientInterceptor(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	internal := status.Error(codes.Internal, "internal")
+
+	tests := []struct {
+		Name     string
+		Streams  []*fakeStream
+		Options  []StreamOption
+		Received []string
+		Requests []string
+		Opened   int
+		Error    codes.Code
+	}{
+		{
+			Name: "Stream is re-established",
+			Streams: []*fakeStream{
+				{msgs: []string{"a", "b"}, err: unavailable},
+				{msgs: []string{"c"}, err: io.EOF},
+			},
+			Received: []string{"a", "b", "c"},
+			Requests: []string{"start", "start"},
+			Opened:   2,
+			Error:    codes.OK,
+		},
+		{
+			Name: "Request is resumed",
+			Streams: []*fakeStream{
+				{msgs: []string{"a", "b"}, err: unavailable},
+				{msgs: []string{"c"}, err: io.EOF},
+			},
+			Options: []StreamOption{WithResume(func(req any) (any, error) {
+				next := *req.(*string) + "+"
+				return &next, nil
+			})},
+			Received: []string{"a", "b", "c"},
+			Requests: []string{"start", "start+"},
+			Opened:   2,
+			Error:    codes.OK,
+		},
+		{
+			Name: "Opening is retried",
+			Streams: []*fakeStream{
+				{err: unavailable},
+				{msgs: []string{"a"}, err: io.EOF},
+			},
+			Received: []string{"a"},
+			Requests: []string{"start"},
+			Opened:   2,
+			Error:    codes.OK,
+		},
+		{
+			Name: "Fatal error is returned",
+			Streams: []*fakeStream{
+				{msgs: []string{"a", "b"}, err: internal},
+			},
+			Received: []string{"a", "b"},
+			Requests: []string{"start"},
+			Opened:   1,
+			Error:    codes.Internal,
+		},
+		{
+			Name: "Classifier decides retries",
+			Streams: []*fakeStream{
+				{msgs: []string{"a"}, err: internal},
+				{msgs: []string{"b"}, err: io.EOF},
+			},
+			Options:  []StreamOption{WithClassifier(Codes(codes.Internal))},
+			Received: []string{"a", "b"},
+			Requests: []string{"start", "start"},
+			Opened:   2,
+			Error:    codes.OK,
+		},
+		{
+			Name: "Unavailable after max retries",
+			Streams: []*fakeStream{
+				{msgs: []string{"a"}, err: unavailable},
+				{err: unavailable},
+				{err: unavailable},
+				{err: unavailable},
+			},
+			Received: []string{"a"},
+			Requests: []string{"start"},
+			Opened:   4,
+			Error:    codes.Unavailable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			reqs := []string{}
+			for _, s := range test.Streams {
+				s.reqs = &reqs
+			}
+
+			opened := 0
+			retr := retrier.NewRetrier(2, retrier.ConstantDelay(time.Millisecond))
+			intc := StreamClientInterceptor(retr, test.Options...)
+			cs, err := intc(
+				context.TODO(),
+				&grpc.StreamDesc{ServerStreams: true},
+				nil,
+				"/test.Service/Watch",
+				fakeStreamer(test.Streams, &opened),
+			)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req := "start"
+			assert.NoError(t, cs.SendMsg(&req))
+			assert.NoError(t, cs.CloseSend())
+
+			received := []string{}
+			for {
+				var msg string
+				if err = cs.RecvMsg(&msg); err != nil {
+					break
+				}
+				received = append(received, msg)
+			}
+
+			if test.Error == codes.OK {
+				assert.Equal(t, io.EOF, err)
+			} else {
+				_, ok := status.FromError(err)
+				assert.True(t, ok)
+				assert.Equal(t, test.Error, status.Code(err))
+			}
+			assert.Equal(t, test.Opened, opened)
+			assert.Equal(t, test.Received, received)
+			assert.Equal(t, test.Requests, reqs)
+		})
+	}
+}
+
+// TestStreamClientInterceptorOpen tests if streams are opened with the context
+// of the attempt, and if the status error of the last attempt is returned when
+// opening the stream fails after max retries
+func TestStreamClientInterceptorOpen(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	attempts := []int{}
+	streamer := func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		if att, ok := retrier.AttemptFromContext(ctx); ok {
+			attempts = append(attempts, att.Number)
+		}
+		return nil, unavailable
+	}
+
+	retr := retrier.NewRetrier(2, retrier.ConstantDelay(time.Millisecond))
+	intc := StreamClientInterceptor(retr)
+	_, err := intc(
+		context.TODO(),
+		&grpc.StreamDesc{ServerStreams: true},
+		nil,
+		"/test.Service/Watch",
+		streamer,
+	)
+
+	var exh *retrier.ExhaustedError
+	assert.False(t, errors.As(err, &exh))
+	assert.Equal(t, unavailable, err)
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+}
+
+// TestStreamClientInterceptorRelease tests if the context of a client stream
+// is released once its only message is received
+func TestStreamClientInterceptorRelease(t *testing.T) {
+	var sctx context.Context
+	streamer := func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		sctx = ctx
+		return &fakeStream{msgs: []string{"reply"}, err: io.EOF}, nil
+	}
+
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	retr := retrier.NewRetrier(2, retrier.NoDelay())
+	intc := StreamClientInterceptor(retr)
+	cs, err := intc(
+		parent,
+		&grpc.StreamDesc{ClientStreams: true},
+		nil,
+		"/test.Service/Upload",
+		streamer,
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, sctx.Err())
+
+	msg := ""
+	assert.NoError(t, cs.RecvMsg(&msg))
+	assert.Equal(t, "reply", msg)
+	assert.Eventually(t, func() bool {
+		return sctx.Err() != nil
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,450 @@
+// Package retrierhttp retries HTTP requests with a retrier by wrapping the
+// transport of an http.Client.
+package retrierhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Soreing/retrier"
+)
+
+// StatusError is the error of an attempt that received a response with a
+// status code that is retried.
+type StatusError struct {
+	// Code is the status code of the response.
+	Code int
+}
+
+// Error returns the status code of the response.
+func (e *StatusError) Error() string {
+	return "retryable status code " + strconv.Itoa(e.Code)
+}
+
+// ErrBodyNotReplayable is the reason for refusing to retry a request whose
+// body can not be sent again.
+var ErrBodyNotReplayable = errors.New("request body can not be replayed")
+
+// ReplayError is returned when a request should be retried, but its body can
+// not be sent again. Retrying it would send a partially consumed body, so the
+// request is not retried.
+type ReplayError struct {
+	// Err is the error of the last attempt of the request, or the error of
+	// GetBody if it failed to create a new body.
+	Err error
+}
+
+// Error returns the reason for not retrying followed by the error of the last
+// attempt or of GetBody.
+func (e *ReplayError) Error() string {
+	return ErrBodyNotReplayable.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the last attempt or of GetBody.
+func (e *ReplayError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is ErrBodyNotReplayable.
+func (e *ReplayError) Is(target error) bool {
+	return target == ErrBodyNotReplayable
+}
+
+// Transport is an http.RoundTripper that retries requests with a retrier. It
+// retries requests with idempotent methods that fail with a connection error,
+// or that receive a response with a retryable status code. When the retrier
+// gives up on a request that received a response, the last response is
+// returned as it is, the same way a client without retries would return it.
+type Transport struct {
+	retr     *retrier.Retrier
+	base     http.RoundTripper
+	classify func(code int) bool
+	methods  map[string]bool
+	maxWait  time.Duration
+	maxBody  int64
+}
+
+// Option configures a transport.
+type Option func(*Transport)
+
+// DefaultStatusCodes are the status codes of responses that are retried by
+// default.
+var DefaultStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultMaxBodyBuffer is the default size limit in bytes of request bodies
+// that are buffered to be replayed.
+const DefaultMaxBodyBuffer = 64 << 10
+
+// DefaultMethods are the idempotent methods of requests that are retried by
+// default.
+var DefaultMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// StatusCodes returns a status classifier that retries responses with any of
+// the status codes.
+func StatusCodes(codes ...int) func(code int) bool {
+	set := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return func(code int) bool {
+		return set[code]
+	}
+}
+
+// WithStatusCodes sets the status codes of responses that are retried,
+// replacing DefaultStatusCodes.
+func WithStatusCodes(codes ...int) Option {
+	return WithStatusClassifier(StatusCodes(codes...))
+}
+
+// WithStatusClassifier sets a classifier that decides whether a response with
+// a status code is retried, replacing DefaultStatusCodes.
+func WithStatusClassifier(classify func(code int) bool) Option {
+	return func(t *Transport) {
+		t.classify = classify
+	}
+}
+
+// WithMethods sets the methods of requests that are retried, replacing
+// DefaultMethods. Requests with other methods are sent once. Methods that are
+// not idempotent, such as POST, should only be allowed if the server
+// deduplicates the requests, for example with idempotency keys.
+func WithMethods(methods ...string) Option {
+	return func(t *Transport) {
+		t.methods = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			t.methods[method] = true
+		}
+	}
+}
+
+// DefaultMaxRetryAfter is the default cap of the delay that a server can
+// request with the headers of a response.
+const DefaultMaxRetryAfter = 5 * time.Minute
+
+// WithMaxRetryAfter caps the delay that a server can request with the headers
+// of a response, replacing DefaultMaxRetryAfter. The cap is disabled when the
+// value is not positive, which lets the server stall requests for as long as
+// it asks, so it should only be disabled for trusted servers.
+func WithMaxRetryAfter(d time.Duration) Option {
+	return func(t *Transport) {
+		t.maxWait = d
+	}
+}
+
+// WithMaxBodyBuffer sets the size limit in bytes of request bodies that are
+// buffered in memory to be replayed when the request has no GetBody function.
+// Larger bodies are not buffered, so their requests are not retried. Buffering
+// is disabled when the value is not positive.
+func WithMaxBodyBuffer(n int64) Option {
+	return func(t *Transport) {
+		t.maxBody = n
+	}
+}
+
+// NewTransport creates a transport that retries requests sent with a base
+// transport using a retrier. If the base transport is nil,
+// http.DefaultTransport is used.
+func NewTransport(
+	r *retrier.Retrier,
+	base http.RoundTripper,
+	opts ...Option,
+) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &Transport{
+		retr:     r,
+		base:     base,
+		classify: StatusCodes(DefaultStatusCodes...),
+		maxWait:  DefaultMaxRetryAfter,
+		maxBody:  DefaultMaxBodyBuffer,
+	}
+	WithMethods(DefaultMethods...)(t)
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip sends a request with the base transport, and retries it according
+// to the retrier if its method allows it. The body of the request is replayed
+// with GetBody, or buffered if it is small enough. When a request with a body
+// that can not be replayed should be retried, the retrier stops with a
+// *ReplayError instead.
+//
+// Each attempt sends the request with the context of the attempt, so its
+// values and timeout apply to the round trip. The body of the response is
+// read after the attempt has ended, so the timeout of the attempt only
+// applies until the response is received.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.retryable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	next, err := t.replay(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := req.Context()
+	attempt := 0
+	var last error
+	var resp *http.Response
+	err = t.retr.RunCtx(ctx, func(actx context.Context) (error, bool) {
+		// The response is returned after the attempt has ended, so the
+		// request is sent with a context that is detached from the attempt
+		// once the response is received, and released when its body is
+		// closed.
+		sctx, detach, cancel := retrier.DetachAttempt(ctx, actx)
+		areq, err := next(sctx, attempt)
+		attempt++
+		if err != nil {
+			cancel()
+			if err == ErrBodyNotReplayable {
+				err = last
+			}
+			return &ReplayError{Err: err}, false
+		}
+		if resp != nil {
+			discard(resp)
+			resp = nil
+		}
+
+		res, err := t.base.RoundTrip(areq)
+		if err != nil {
+			cancel()
+			last = err
+			timedOut := actx.Err() != nil
+			return err, ctx.Err() == nil && (timedOut || Retryable(err))
+		}
+
+		detach()
+		res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+		resp = res
+		if t.classify(res.StatusCode) {
+			last = &StatusError{Code: res.StatusCode}
+			if wait, ok := RetryAfter(res.Header, t.maxWait); ok {
+				return retrier.RetryAfter(last, wait), true
+			}
+			return last, true
+		}
+		return nil, false
+	})
+
+	var serr *StatusError
+	if err == nil || (resp != nil && errors.As(err, &serr)) {
+		return resp, nil
+	}
+	if resp != nil {
+		discard(resp)
+	}
+	return nil, err
+}
+
+// Retryable reports whether an error of a round trip is a connection error,
+// so the request can be retried. It recognizes network errors, such as
+// refused or reset connections and timeouts, and connections closed by the
+// server. TLS handshake failures, DNS lookups of hosts that do not exist,
+// invalid requests and context errors are not retryable.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	} else if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var operr *net.OpError
+	if errors.As(err, &operr) && operr.Op == "remote error" {
+		// Alerts of the TLS handshake sent by the server.
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	var recErr tls.RecordHeaderError
+	if errors.As(err, &certErr) || errors.As(err, &recErr) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var nerr net.Error
+	return errors.As(err, &nerr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retryable reports whether the method of a request allows it to be retried.
+// Requests without a method are GET requests.
+func (t *Transport) retryable(req *http.Request) bool {
+	if req.Method == "" {
+		return t.methods[http.MethodGet]
+	}
+	return t.methods[req.Method]
+}
+
+// RetryAfter returns the delay that a server requests before retrying from
+// the headers of a response. The headers are checked in order, which are
+// retry-after-ms and x-ms-retry-after-ms in milliseconds, Retry-After in
+// seconds or as an HTTP date, and X-RateLimit-Reset as a unix time or in
+// seconds. Delays in the past are zero, and the delay is capped by a max delay
+// unless it is not positive.
+func RetryAfter(header http.Header, max time.Duration) (time.Duration, bool) {
+	wait, ok := time.Duration(0), false
+	for _, key := range []string{"Retry-After-Ms", "X-Ms-Retry-After-Ms"} {
+		if ms, err := strconv.ParseInt(header.Get(key), 10, 64); err == nil {
+			wait, ok = time.Duration(ms)*time.Millisecond, true
+			break
+		}
+	}
+	if !ok {
+		wait, ok = parseRetryAfter(header.Get("Retry-After"))
+	}
+	if !ok {
+		wait, ok = parseRateLimitReset(header.Get("X-RateLimit-Reset"))
+	}
+	if !ok {
+		return 0, false
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+	if max > 0 && wait > max {
+		wait = max
+	}
+	return wait, true
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func parseRetryAfter(val string) (time.Duration, bool) {
+	if val == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if date, err := http.ParseTime(val); err == nil {
+		return time.Until(date), true
+	}
+	return 0, false
+}
+
+// parseRateLimitReset parses the value of an X-RateLimit-Reset header, which
+// is either a unix time in seconds or a number of seconds until the reset.
+// Values too large to be a reasonable number of seconds are unix times.
+func parseRateLimitReset(val string) (time.Duration, bool) {
+	if val == "" {
+		return 0, false
+	}
+	secs, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, false
+	}
+	if secs > 1e9 {
+		return time.Until(time.Unix(0, int64(secs*1e9))), true
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
+
+// replay prepares a request to be sent more than once, and returns a function
+// that returns the request to send with a context for each attempt. The first
+// attempt sends the body of the original request, or a buffered body, while
+// later attempts send a new body. If the body can not be replayed, the
+// function returns ErrBodyNotReplayable for later attempts.
+func (t *Transport) replay(
+	req *http.Request,
+) (func(ctx context.Context, attempt int) (*http.Request, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return func(ctx context.Context, _ int) (*http.Request, error) {
+			return req.Clone(ctx), nil
+		}, nil
+	}
+
+	first := req
+	getBody := req.GetBody
+	if getBody == nil && t.maxBody > 0 {
+		buf, err := io.ReadAll(io.LimitReader(req.Body, t.maxBody+1))
+		if err != nil {
+			req.Body.Close()
+			return nil, err
+		}
+
+		first = req.Clone(req.Context())
+		if int64(len(buf)) <= t.maxBody {
+			req.Body.Close()
+			getBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(buf)), nil
+			}
+			first.Body, _ = getBody()
+		} else {
+			first.Body = &struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+		}
+	}
+
+	return func(ctx context.Context, attempt int) (*http.Request, error) {
+		if attempt == 0 {
+			return first.Clone(ctx), nil
+		} else if getBody == nil {
+			return nil, ErrBodyNotReplayable
+		}
+
+		body, err := getBody()
+		if err != nil {
+			return nil, err
+		}
+		areq := req.Clone(ctx)
+		areq.Body = body
+		return areq, nil
+	}, nil
+}
+
+// cancelBody is the body of a response that releases the context of its
+// request when it is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and releases the context of the request.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// discard reads and closes the body of a response that is not returned, so
+// that its connection can be reused.
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
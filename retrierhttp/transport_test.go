@@ -0,0 +1,542 @@
+package retrierhttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+)
+
+// roundTripperFunc is a round tripper implemented by a function.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestTransport tests if requests are retried on retryable status codes only
+// when they are idempotent, and the last response is returned when the
+// retrier gives up
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Method   string
+		Body     []byte
+		Statuses []int
+		Status   int
+		Requests int
+	}{
+		{
+			Name:     "Retried until success",
+			Method:   http.MethodGet,
+			Statuses: []int{503, 503, 200},
+			Status:   200,
+			Requests: 3,
+		},
+		{
+			Name:     "Last response after max retries",
+			Method:   http.MethodGet,
+			Statuses: []int{502, 503, 504, 200},
+			Status:   504,
+			Requests: 3,
+		},
+		{
+			Name:     "Status is not retried",
+			Method:   http.MethodGet,
+			Statuses: []int{404, 200},
+			Status:   404,
+			Requests: 1,
+		},
+		{
+			Name:     "Method is not idempotent",
+			Method:   http.MethodPost,
+			Statuses: []int{503, 200},
+			Status:   503,
+			Requests: 1,
+		},
+		{
+			Name:     "Body is replayed",
+			Method:   http.MethodPut,
+			Body:     []byte("payload"),
+			Statuses: []int{503, 200},
+			Status:   200,
+			Requests: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					body, _ := io.ReadAll(r.Body)
+					assert.Equal(t, string(test.Body), string(body))
+					w.WriteHeader(test.Statuses[requests])
+					requests++
+				},
+			))
+			defer srv.Close()
+
+			retr := retrier.NewRetrier(2, retrier.ConstantDelay(time.Millisecond))
+			client := &http.Client{Transport: NewTransport(retr, nil)}
+
+			req, _ := http.NewRequest(test.Method, srv.URL, bytes.NewReader(test.Body))
+			resp, err := client.Do(req)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.Status, resp.StatusCode)
+				resp.Body.Close()
+			}
+			assert.Equal(t, test.Requests, requests)
+		})
+	}
+}
+
+// TestTransportErrors tests if requests are retried on connection errors only,
+// and the error is returned when the retrier gives up
+func TestTransportErrors(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+
+	tests := []struct {
+		Name      string
+		Err       error
+		Fails     int
+		Requests  int
+		Exhausted bool
+	}{
+		{
+			Name:      "Retried until success",
+			Err:       reset,
+			Fails:     2,
+			Requests:  3,
+			Exhausted: false,
+		},
+		{
+			Name:      "Error after max retries",
+			Err:       reset,
+			Fails:     5,
+			Requests:  3,
+			Exhausted: true,
+		},
+		{
+			Name:      "Unsupported scheme is not retried",
+			Err:       errors.New(`unsupported protocol scheme "ftp"`),
+			Fails:     5,
+			Requests:  1,
+			Exhausted: false,
+		},
+		{
+			Name:      "TLS alert is not retried",
+			Err:       &net.OpError{Op: "remote error", Err: errors.New("tls: bad certificate")},
+			Fails:     5,
+			Requests:  1,
+			Exhausted: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			requests := 0
+			base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				requests++
+				if requests <= test.Fails {
+					return nil, test.Err
+				}
+				return &http.Response{
+					StatusCode: 200,
+					Body:       io.NopCloser(bytes.NewReader(nil)),
+				}, nil
+			})
+
+			retr := retrier.NewRetrier(2, retrier.ConstantDelay(time.Millisecond))
+			req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+			resp, err := NewTransport(retr, base).RoundTrip(req)
+
+			if test.Fails < test.Requests {
+				assert.NoError(t, err)
+				assert.Equal(t, 200, resp.StatusCode)
+			} else {
+				assert.ErrorIs(t, err, test.Err)
+				assert.Equal(t, test.Exhausted, errors.Is(err, retrier.ErrMaxRetriesExceeded))
+				assert.Nil(t, resp)
+			}
+			assert.Equal(t, test.Requests, requests)
+		})
+	}
+}
+
+// TestTransportPolicy tests if the status classifier and the methods of the
+// transport decide which requests are retried
+func TestTransportPolicy(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Options  []Option
+		Method   string
+		Status   int
+		Requests int
+	}{
+		{
+			Name:     "Too many requests by default",
+			Options:  []Option{},
+			Method:   http.MethodGet,
+			Status:   429,
+			Requests: 3,
+		},
+		{
+			Name:     "Custom status code",
+			Options:  []Option{WithStatusCodes(500)},
+			Method:   http.MethodGet,
+			Status:   500,
+			Requests: 3,
+		},
+		{
+			Name:     "Default status code replaced",
+			Options:  []Option{WithStatusCodes(500)},
+			Method:   http.MethodGet,
+			Status:   503,
+			Requests: 1,
+		},
+		{
+			Name: "Status classifier",
+			Options: []Option{WithStatusClassifier(func(code int) bool {
+				return code >= 500
+			})},
+			Method:   http.MethodGet,
+			Status:   501,
+			Requests: 3,
+		},
+		{
+			Name:     "Allowed method",
+			Options:  []Option{WithMethods(http.MethodPost)},
+			Method:   http.MethodPost,
+			Status:   503,
+			Requests: 3,
+		},
+		{
+			Name:     "Default method replaced",
+			Options:  []Option{WithMethods(http.MethodPost)},
+			Method:   http.MethodGet,
+			Status:   503,
+			Requests: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			requests := 0
+			base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				requests++
+				return &http.Response{
+					StatusCode: test.Status,
+					Body:       io.NopCloser(bytes.NewReader(nil)),
+				}, nil
+			})
+
+			retr := retrier.NewRetrier(2, retrier.NoDelay())
+			req, _ := http.NewRequest(test.Method, "http://example.com", nil)
+			resp, err := NewTransport(retr, base, test.Options...).RoundTrip(req)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.Status, resp.StatusCode)
+			}
+			assert.Equal(t, test.Requests, requests)
+		})
+	}
+}
+
+// instantClock is a clock that does not wait.
+type instantClock struct{}
+
+func (instantClock) Now() time.Time {
+	return time.Now()
+}
+
+func (instantClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+// TestTransportRetryAfter tests if the delay requested by the server in the
+// headers of a response is used as the next delay, capped by the transport
+func TestTransportRetryAfter(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Header  string
+		Value   string
+		Options []Option
+		Delay   time.Duration
+	}{
+		{
+			Name:   "No header",
+			Header: "",
+			Value:  "",
+			Delay:  time.Millisecond,
+		},
+		{
+			Name:   "Retry-After seconds",
+			Header: "Retry-After",
+			Value:  "120",
+			Delay:  time.Minute * 2,
+		},
+		{
+			Name:   "Retry-After date",
+			Header: "Retry-After",
+			Value:  time.Now().Add(time.Minute * 2).UTC().Format(http.TimeFormat),
+			Delay:  time.Minute * 2,
+		},
+		{
+			Name:   "Retry-After invalid",
+			Header: "Retry-After",
+			Value:  "soon",
+			Delay:  time.Millisecond,
+		},
+		{
+			Name:    "Retry-After capped",
+			Header:  "Retry-After",
+			Value:   "120",
+			Options: []Option{WithMaxRetryAfter(time.Minute)},
+			Delay:   time.Minute,
+		},
+		{
+			Name:   "Retry-After capped by default",
+			Header: "Retry-After",
+			Value:  "999999999",
+			Delay:  DefaultMaxRetryAfter,
+		},
+		{
+			Name:    "Retry-After cap disabled",
+			Header:  "Retry-After",
+			Value:   "7200",
+			Options: []Option{WithMaxRetryAfter(0)},
+			Delay:   time.Hour * 2,
+		},
+		{
+			Name:   "X-RateLimit-Reset seconds",
+			Header: "X-RateLimit-Reset",
+			Value:  "30",
+			Delay:  time.Second * 30,
+		},
+		{
+			Name:   "X-RateLimit-Reset unix time",
+			Header: "X-RateLimit-Reset",
+			Value:  fmt.Sprint(time.Now().Add(time.Minute).Unix()),
+			Delay:  time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				header := http.Header{}
+				if test.Header != "" {
+					header.Set(test.Header, test.Value)
+				}
+				return &http.Response{
+					StatusCode: 429,
+					Header:     header,
+					Body:       io.NopCloser(bytes.NewReader(nil)),
+				}, nil
+			})
+
+			delays := []time.Duration{}
+			retr := retrier.NewRetrier(
+				1,
+				retrier.ConstantDelay(time.Millisecond),
+				retrier.WithClock(instantClock{}),
+				retrier.WithOnRetry(func(_ int, _ error, delay time.Duration) {
+					delays = append(delays, delay)
+				}),
+			)
+			req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+			_, err := NewTransport(retr, base, test.Options...).RoundTrip(req)
+
+			assert.NoError(t, err)
+			if assert.Len(t, delays, 1) {
+				assert.InDelta(
+					t,
+					float64(test.Delay),
+					float64(delays[0]),
+					float64(time.Second*2),
+				)
+			}
+		})
+	}
+}
+
+// TestTransportBody tests if request bodies are replayed with GetBody or
+// buffered, and requests with bodies that can not be replayed are not retried
+func TestTransportBody(t *testing.T) {
+	tests := []struct {
+		Name     string
+		GetBody  bool
+		BodyErr  error
+		Options  []Option
+		Fail     bool
+		Requests int
+		Error    error
+	}{
+		{
+			Name:     "Replayed with GetBody",
+			GetBody:  true,
+			Options:  []Option{WithMaxBodyBuffer(0)},
+			Requests: 3,
+		},
+		{
+			Name:     "Replayed from buffer",
+			GetBody:  false,
+			Options:  []Option{},
+			Requests: 3,
+		},
+		{
+			Name:     "Response returned when body is too large",
+			GetBody:  false,
+			Options:  []Option{WithMaxBodyBuffer(4)},
+			Requests: 1,
+		},
+		{
+			Name:     "Error returned when body is too large",
+			GetBody:  false,
+			Options:  []Option{WithMaxBodyBuffer(4)},
+			Fail:     true,
+			Requests: 1,
+			Error:    fmt.Errorf("request body can not be replayed: connection reset by peer"),
+		},
+		{
+			Name:     "Error of GetBody is returned",
+			GetBody:  true,
+			BodyErr:  fmt.Errorf("body is gone"),
+			Options:  []Option{WithMaxBodyBuffer(0)},
+			Fail:     true,
+			Requests: 1,
+			Error:    fmt.Errorf("request body can not be replayed: body is gone"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			requests := 0
+			base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				requests++
+				body, _ := io.ReadAll(req.Body)
+				assert.Equal(t, "payload", string(body))
+				if test.Fail {
+					return nil, syscall.ECONNRESET
+				}
+				return &http.Response{
+					StatusCode: 503,
+					Body:       io.NopCloser(bytes.NewReader(nil)),
+				}, nil
+			})
+
+			req, _ := http.NewRequest(
+				http.MethodPut,
+				"http://example.com",
+				io.NopCloser(bytes.NewReader([]byte("payload"))),
+			)
+			if test.GetBody {
+				req.GetBody = func() (io.ReadCloser, error) {
+					if test.BodyErr != nil {
+						return nil, test.BodyErr
+					}
+					return io.NopCloser(bytes.NewReader([]byte("payload"))), nil
+				}
+			}
+
+			retr := retrier.NewRetrier(2, retrier.NoDelay())
+			resp, err := NewTransport(retr, base, test.Options...).RoundTrip(req)
+
+			if test.Error != nil {
+				assert.EqualError(t, err, test.Error.Error())
+				assert.ErrorIs(t, err, ErrBodyNotReplayable)
+				var rerr *ReplayError
+				assert.ErrorAs(t, err, &rerr)
+				if test.BodyErr != nil {
+					assert.ErrorIs(t, err, test.BodyErr)
+				}
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, 503, resp.StatusCode)
+			}
+			assert.Equal(t, test.Requests, requests)
+		})
+	}
+}
+
+// TestTransportAttemptContext tests if requests are sent with the context of
+// the attempt, so the attempt timeout cuts off hanging requests, and if the
+// body of the response can still be read after the attempt has ended
+func TestTransportAttemptContext(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 1 {
+				<-r.Context().Done()
+				return
+			}
+			w.WriteHeader(200)
+			w.(http.Flusher).Flush()
+			time.Sleep(time.Millisecond * 40)
+			w.Write([]byte("payload"))
+		},
+	))
+	defer srv.Close()
+
+	numbers := []int{}
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		att, _ := retrier.AttemptFromContext(req.Context())
+		numbers = append(numbers, att.Number)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	retr := retrier.NewRetrier(2, retrier.NoDelay(),
+		retrier.WithAttemptTimeout(time.Millisecond*20),
+	)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := NewTransport(retr, base).RoundTrip(req)
+
+	if assert.NoError(t, err) {
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "payload", string(body))
+		resp.Body.Close()
+	}
+	assert.Equal(t, []int{1, 2}, numbers)
+}
+
+// TestRetryAfter tests if the delays requested by the headers of responses
+// are parsed in milliseconds, seconds and HTTP dates, and capped
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Header http.Header
+		Max    time.Duration
+		Delay  string
+		Ok     bool
+	}{
+		{Name: "Milliseconds", Header: http.Header{"Retry-After-Ms": {"250"}}, Delay: "250ms", Ok: true},
+		{Name: "Azure milliseconds", Header: http.Header{"X-Ms-Retry-After-Ms": {"100"}}, Delay: "100ms", Ok: true},
+		{Name: "Milliseconds first", Header: http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"3"}}, Delay: "250ms", Ok: true},
+		{Name: "Seconds", Header: http.Header{"Retry-After": {"3"}}, Delay: "3s", Ok: true},
+		{Name: "Date in the past", Header: http.Header{"Retry-After": {"Mon, 02 Jan 2006 15:04:05 GMT"}}, Delay: "0s", Ok: true},
+		{Name: "Capped", Header: http.Header{"Retry-After": {"3600"}}, Max: time.Minute, Delay: "1m0s", Ok: true},
+		{Name: "No header", Header: http.Header{}, Delay: "0s", Ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			delay, ok := RetryAfter(test.Header, test.Max)
+			assert.Equal(t, test.Ok, ok)
+			assert.Equal(t, test.Delay, delay.String())
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package retrierkafka
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/Soreing/retrier"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Header keys of the records produced to dead letter topics, which describe
+// the record that failed to be processed.
+const (
+	HeaderError     = "retrier-error"
+	HeaderTopic     = "retrier-topic"
+	HeaderPartition = "retrier-partition"
+	HeaderOffset    = "retrier-offset"
+)
+
+// Handler processes a record that was consumed.
+type Handler func(ctx context.Context, rec *kgo.Record) error
+
+// DeadLetterFunc hands off a record that failed to be processed along with the
+// error of processing it, for example by producing it to a dead letter topic.
+type DeadLetterFunc func(ctx context.Context, rec *kgo.Record, err error) error
+
+// Committer is the interface of the clients that commit the offsets of
+// records, which is implemented by *kgo.Client.
+type Committer interface {
+	CommitRecords(ctx context.Context, rs ...*kgo.Record) error
+}
+
+// Process processes a record with a handler, and retries it according to the
+// retrier when the handler fails. Errors are retried unless they are
+// permanent. When the retrier gives up, the record is handed off to the dead
+// letter function, and processing it succeeds if the hand-off does. If the
+// dead letter function is nil, there is no dead letter queue, and the error of
+// processing the record is returned. If the context is done, the record is not
+// handed off, so it can be processed again after a restart.
+func Process(
+	ctx context.Context,
+	r *retrier.Retrier,
+	rec *kgo.Record,
+	handle Handler,
+	deadLetter DeadLetterFunc,
+) error {
+	err := r.RunCtxErr(ctx, func(ctx context.Context) error {
+		return handle(ctx, rec)
+	})
+	if err == nil {
+		return nil
+	} else if ctx.Err() != nil || deadLetter == nil {
+		return err
+	}
+
+	if derr := deadLetter(ctx, rec, err); derr != nil {
+		return errors.Join(err, derr)
+	}
+	return nil
+}
+
+// ProcessCommit processes a record the same way as Process, and commits the
+// offset of the record when it was processed or handed off. The offset is not
+// committed if both processing and the hand-off failed.
+func ProcessCommit(
+	ctx context.Context,
+	r *retrier.Retrier,
+	c Committer,
+	rec *kgo.Record,
+	handle Handler,
+	deadLetter DeadLetterFunc,
+) error {
+	if err := Process(ctx, r, rec, handle, deadLetter); err != nil {
+		return err
+	}
+	return c.CommitRecords(ctx, rec)
+}
+
+// DeadLetterTopic returns a dead letter function that produces records to a
+// topic with a producer. The records keep their keys, values and headers, and
+// headers are added with the error, topic, partition and offset of the
+// record that failed.
+func DeadLetterTopic(p Producer, topic string) DeadLetterFunc {
+	return func(ctx context.Context, rec *kgo.Record, err error) error {
+		headers := make([]kgo.RecordHeader, 0, len(rec.Headers)+4)
+		headers = append(headers, rec.Headers...)
+		headers = append(headers,
+			kgo.RecordHeader{Key: HeaderError, Value: []byte(err.Error())},
+			kgo.RecordHeader{Key: HeaderTopic, Value: []byte(rec.Topic)},
+			kgo.RecordHeader{
+				Key:   HeaderPartition,
+				Value: []byte(strconv.FormatInt(int64(rec.Partition), 10)),
+			},
+			kgo.RecordHeader{
+				Key:   HeaderOffset,
+				Value: []byte(strconv.FormatInt(rec.Offset, 10)),
+			},
+		)
+
+		return p.ProduceSync(ctx, &kgo.Record{
+			Topic:   topic,
+			Key:     rec.Key,
+			Value:   rec.Value,
+			Headers: headers,
+		}).FirstErr()
+	}
+}
@@ -0,0 +1,187 @@
+package retrierkafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// committerFunc is a committer that commits records with a function.
+type committerFunc func(rs ...*kgo.Record) error
+
+func (f committerFunc) CommitRecords(
+	ctx context.Context,
+	rs ...*kgo.Record,
+) error {
+	return f(rs...)
+}
+
+// TestProcessCommit tests if records are processed with retries, handed off
+// to the dead letter function when processing fails, and committed unless
+// both processing and the hand-off fail, or there is no dead letter function
+func TestProcessCommit(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Errs       []error
+		DeadErr    error
+		NoDLQ      bool
+		Attempts   int
+		DeadLetter bool
+		Committed  bool
+		Err        error
+	}{
+		{
+			Name:       "Success",
+			Errs:       []error{nil},
+			Attempts:   1,
+			DeadLetter: false,
+			Committed:  true,
+			Err:        nil,
+		},
+		{
+			Name:       "Success after retries",
+			Errs:       []error{errors.New("error"), nil},
+			Attempts:   2,
+			DeadLetter: false,
+			Committed:  true,
+			Err:        nil,
+		},
+		{
+			Name:       "Exhausted",
+			Errs:       []error{errors.New("error"), errors.New("error"), errors.New("error")},
+			Attempts:   3,
+			DeadLetter: true,
+			Committed:  true,
+			Err:        nil,
+		},
+		{
+			Name:       "Permanent",
+			Errs:       []error{retrier.Permanent(errors.New("error"))},
+			Attempts:   1,
+			DeadLetter: true,
+			Committed:  true,
+			Err:        nil,
+		},
+		{
+			Name:       "Dead letter failure",
+			Errs:       []error{retrier.Permanent(errors.New("error"))},
+			DeadErr:    errors.New("dead letter error"),
+			Attempts:   1,
+			DeadLetter: true,
+			Committed:  false,
+			Err:        errors.New("error\ndead letter error"),
+		},
+		{
+			Name:       "No dead letter queue",
+			Errs:       []error{retrier.Permanent(errors.New("error"))},
+			NoDLQ:      true,
+			Attempts:   1,
+			DeadLetter: false,
+			Committed:  false,
+			Err:        errors.New("error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			attempts := 0
+			handle := func(ctx context.Context, rec *kgo.Record) error {
+				err := test.Errs[attempts]
+				attempts++
+				return err
+			}
+
+			deadLetter := false
+			var dlq DeadLetterFunc = func(ctx context.Context, rec *kgo.Record, err error) error {
+				deadLetter = true
+				return test.DeadErr
+			}
+			if test.NoDLQ {
+				dlq = nil
+			}
+
+			committed := false
+			c := committerFunc(func(rs ...*kgo.Record) error {
+				committed = true
+				return nil
+			})
+
+			retr := retrier.NewRetrier(2, retrier.NoDelay())
+			err := ProcessCommit(
+				context.Background(), retr, c, &kgo.Record{}, handle, dlq,
+			)
+
+			if test.Err != nil {
+				assert.EqualError(t, err, test.Err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.Attempts, attempts)
+			assert.Equal(t, test.DeadLetter, deadLetter)
+			assert.Equal(t, test.Committed, committed)
+		})
+	}
+}
+
+// TestProcessCanceled tests if records are not handed off to the dead letter
+// function when the context is done
+func TestProcessCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	handle := func(ctx context.Context, rec *kgo.Record) error {
+		cancel()
+		return errors.New("error")
+	}
+
+	deadLetter := false
+	dlq := func(ctx context.Context, rec *kgo.Record, err error) error {
+		deadLetter = true
+		return nil
+	}
+
+	retr := retrier.NewRetrier(2, retrier.ConstantDelay(time.Millisecond))
+	err := Process(ctx, retr, &kgo.Record{}, handle, dlq)
+
+	assert.Error(t, err)
+	assert.False(t, deadLetter)
+}
+
+// TestDeadLetterTopic tests if records are produced to the dead letter topic
+// with headers describing the record that failed
+func TestDeadLetterTopic(t *testing.T) {
+	var produced *kgo.Record
+	p := producerFunc(func(rec *kgo.Record) error {
+		produced = rec
+		return nil
+	})
+
+	rec := &kgo.Record{
+		Topic:     "events",
+		Partition: 2,
+		Offset:    15,
+		Key:       []byte("key"),
+		Value:     []byte("value"),
+		Headers:   []kgo.RecordHeader{{Key: "trace", Value: []byte("id")}},
+	}
+	err := DeadLetterTopic(p, "events-dlq")(
+		context.Background(), rec, errors.New("error"),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &kgo.Record{
+		Topic: "events-dlq",
+		Key:   []byte("key"),
+		Value: []byte("value"),
+		Headers: []kgo.RecordHeader{
+			{Key: "trace", Value: []byte("id")},
+			{Key: HeaderError, Value: []byte("error")},
+			{Key: HeaderTopic, Value: []byte("events")},
+			{Key: HeaderPartition, Value: []byte("2")},
+			{Key: HeaderOffset, Value: []byte("15")},
+		},
+	}, produced)
+}
@@ -0,0 +1,20 @@
+module github.com/Soreing/retrier/retrierkafka
+
+go 1.20
+
+require (
+	github.com/Soreing/retrier v0.0.0
+	github.com/stretchr/testify v1.8.4
+	github.com/twmb/franz-go v1.14.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/klauspost/compress v1.16.7 // indirect
+	github.com/pierrec/lz4/v4 v4.1.18 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/twmb/franz-go/pkg/kmsg v1.6.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/Soreing/retrier => ../
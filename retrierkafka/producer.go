@@ -0,0 +1,69 @@
+// Package retrierkafka retries producing and processing Kafka records of
+// franz-go clients with a retrier.
+package retrierkafka
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/Soreing/retrier"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Producer is the interface of the clients that produce records, which is
+// implemented by *kgo.Client.
+type Producer interface {
+	ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults
+}
+
+// Retryable reports whether an error of producing a record is transient, so
+// the record can be produced again. It recognizes the retriable errors of
+// brokers, such as NotLeaderForPartition and RequestTimedOut, records that
+// timed out or ran out of retries in the client, and network errors. Other
+// errors, such as failed authorization or records that can not be serialized,
+// are not retryable.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	} else if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var nerr net.Error
+	return kerr.IsRetriable(err) ||
+		errors.Is(err, kgo.ErrRecordTimeout) ||
+		errors.Is(err, kgo.ErrRecordRetries) ||
+		errors.As(err, &nerr)
+}
+
+// Produce synchronously produces records with a producer, and retries the
+// records that fail with retryable errors according to the retrier. Records
+// that were produced are not sent again. If a record fails with an error that
+// is not retryable, the retrier stops with that error.
+func Produce(
+	ctx context.Context,
+	r *retrier.Retrier,
+	p Producer,
+	rs ...*kgo.Record,
+) error {
+	pending := rs
+	return r.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		var failed []*kgo.Record
+		var last error
+		for _, res := range p.ProduceSync(ctx, pending...) {
+			if res.Err == nil {
+				continue
+			} else if !Retryable(res.Err) {
+				return res.Err, false
+			}
+			failed = append(failed, res.Record)
+			last = res.Err
+		}
+
+		pending = failed
+		return last, last != nil
+	})
+}
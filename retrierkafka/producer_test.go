@@ -0,0 +1,112 @@
+package retrierkafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// producerFunc is a producer that produces each record with a function.
+type producerFunc func(rec *kgo.Record) error
+
+func (f producerFunc) ProduceSync(
+	ctx context.Context,
+	rs ...*kgo.Record,
+) kgo.ProduceResults {
+	res := make(kgo.ProduceResults, 0, len(rs))
+	for _, rec := range rs {
+		res = append(res, kgo.ProduceResult{Record: rec, Err: f(rec)})
+	}
+	return res
+}
+
+// TestRetryable tests if retriable broker errors and client timeouts are
+// retryable, while other errors are not
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Err       error
+		Retryable bool
+	}{
+		{Name: "Nil", Err: nil, Retryable: false},
+		{Name: "Not leader", Err: kerr.NotLeaderForPartition, Retryable: true},
+		{Name: "Request timed out", Err: kerr.RequestTimedOut, Retryable: true},
+		{Name: "Wrapped broker error", Err: fmt.Errorf("produce: %w", kerr.NotLeaderForPartition), Retryable: true},
+		{Name: "Record timeout", Err: kgo.ErrRecordTimeout, Retryable: true},
+		{Name: "Topic authorization", Err: kerr.TopicAuthorizationFailed, Retryable: false},
+		{Name: "Message too large", Err: kerr.MessageTooLarge, Retryable: false},
+		{Name: "Canceled", Err: context.Canceled, Retryable: false},
+		{Name: "Serialization error", Err: errors.New("invalid value"), Retryable: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Retryable, Retryable(test.Err))
+		})
+	}
+}
+
+// TestProduce tests if records that fail with retryable errors are produced
+// again, while produced records are not, and fatal errors stop the retrier
+func TestProduce(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Errs     map[string][]error
+		Produced map[string]int
+		Err      error
+	}{
+		{
+			Name:     "Success",
+			Errs:     map[string][]error{},
+			Produced: map[string]int{"a": 1, "b": 1},
+			Err:      nil,
+		},
+		{
+			Name: "Retry failed record",
+			Errs: map[string][]error{
+				"b": {kerr.NotLeaderForPartition, kerr.RequestTimedOut},
+			},
+			Produced: map[string]int{"a": 1, "b": 3},
+			Err:      nil,
+		},
+		{
+			Name: "Fatal error",
+			Errs: map[string][]error{
+				"a": {kerr.NotLeaderForPartition, kerr.TopicAuthorizationFailed},
+			},
+			Produced: map[string]int{"a": 2, "b": 1},
+			Err:      kerr.TopicAuthorizationFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			produced := map[string]int{}
+			p := producerFunc(func(rec *kgo.Record) error {
+				key := string(rec.Key)
+				produced[key]++
+				if errs := test.Errs[key]; len(errs) > 0 {
+					test.Errs[key] = errs[1:]
+					return errs[0]
+				}
+				return nil
+			})
+
+			retr := retrier.NewRetrier(5, retrier.NoDelay())
+			err := Produce(
+				context.Background(), retr, p,
+				&kgo.Record{Key: []byte("a")},
+				&kgo.Record{Key: []byte("b")},
+			)
+
+			assert.Equal(t, test.Err, err)
+			assert.Equal(t, test.Produced, produced)
+		})
+	}
+}
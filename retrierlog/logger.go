@@ -0,0 +1,96 @@
+// Package retrierlog logs the retries and the outcomes of runs of retriers
+// with any logger that accepts a message and key-value pairs, such as the
+// sugared logger of zap, logr or an adapter of logrus.
+package retrierlog
+
+import (
+	"time"
+
+	"github.com/Soreing/retrier"
+)
+
+// Logger logs a message with alternating keys and values. The keys are
+// strings, and the values are ints, durations and errors.
+type Logger interface {
+	Log(msg string, keyvals ...any)
+}
+
+// LoggerFunc adapts a function to the Logger interface.
+type LoggerFunc func(msg string, keyvals ...any)
+
+// Log calls the function with the message and the key-value pairs.
+func (f LoggerFunc) Log(msg string, keyvals ...any) {
+	f(msg, keyvals...)
+}
+
+// observer is an observer that logs retries and outcomes of runs.
+type observer struct {
+	logger Logger
+}
+
+// WithLogger returns an option of retriers that logs every retry with the
+// number of the attempt that failed, the delay before the next attempt and
+// the error, and the outcome of every run with the number of attempts, the
+// elapsed time and the error if the task did not succeed. The records have
+// the name of the retrier if it has one.
+//
+// The keys are "retrier", "attempt", "delay", "attempts", "elapsed" and
+// "error", and the messages are "retrying task", "task succeeded" and
+// "task failed".
+func WithLogger(l Logger) retrier.Option {
+	return retrier.WithObserver(&observer{logger: l})
+}
+
+// ObserveAttempt does nothing, as attempts are logged when they are retried
+// or when the run ends.
+func (o *observer) ObserveAttempt(name string, rec retrier.AttemptRecord) {}
+
+// ObserveRetry logs a retry of a task.
+func (o *observer) ObserveRetry(
+	name string,
+	attempt int,
+	err error,
+	delay time.Duration,
+) {
+	o.log(name, "retrying task",
+		"attempt", attempt,
+		"delay", delay,
+		"error", err,
+	)
+}
+
+// ObserveLedgerError logs an error of the ledger of a retrier.
+func (o *observer) ObserveLedgerError(
+	name string,
+	rec retrier.AttemptRecord,
+	err error,
+) {
+	o.log(name, "failed to write ledger",
+		"attempt", rec.Attempt,
+		"error", err,
+	)
+}
+
+// ObserveRun logs the outcome of a run.
+func (o *observer) ObserveRun(name string, res retrier.Result, err error) {
+	if err == nil {
+		o.log(name, "task succeeded",
+			"attempts", res.Attempts,
+			"elapsed", res.Elapsed,
+		)
+	} else {
+		o.log(name, "task failed",
+			"attempts", res.Attempts,
+			"elapsed", res.Elapsed,
+			"error", err,
+		)
+	}
+}
+
+// log logs a message with the name of the retrier if it has one.
+func (o *observer) log(name string, msg string, keyvals ...any) {
+	if name != "" {
+		keyvals = append([]any{"retrier", name}, keyvals...)
+	}
+	o.logger.Log(msg, keyvals...)
+}
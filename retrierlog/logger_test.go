@@ -0,0 +1,100 @@
+package retrierlog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWithLogger tests if retries, errors of the ledger and outcomes of runs
+// are logged with keys and values, and with the name of the retrier only if it
+// has one
+func TestWithLogger(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Retrier string
+		Fails   int
+		Ledger  error
+		Lines   []string
+	}{
+		{
+			Name:    "Success",
+			Retrier: "users",
+			Fails:   1,
+			Lines: []string{
+				"retrying task [retrier users attempt 1 delay 5ms error error]",
+				"task succeeded [retrier users attempts 2 elapsed <nil>]",
+			},
+		},
+		{
+			Name:    "Exhausted",
+			Retrier: "users",
+			Fails:   5,
+			Lines: []string{
+				"retrying task [retrier users attempt 1 delay 5ms error error]",
+				"task failed [retrier users attempts 2 elapsed <nil> error failed after max retries: error]",
+			},
+		},
+		{
+			Name:    "No name",
+			Retrier: "",
+			Fails:   0,
+			Lines: []string{
+				"task succeeded [attempts 1 elapsed <nil>]",
+			},
+		},
+		{
+			Name:    "Ledger error",
+			Retrier: "users",
+			Fails:   0,
+			Ledger:  fmt.Errorf("database error"),
+			Lines: []string{
+				"failed to write ledger [retrier users attempt 1 error database error]",
+				"task succeeded [retrier users attempts 1 elapsed <nil>]",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			lines := []string{}
+			logger := LoggerFunc(func(msg string, keyvals ...any) {
+				// Elapsed times vary, so they are left out of the lines.
+				for i := 0; i+1 < len(keyvals); i += 2 {
+					if keyvals[i] == "elapsed" {
+						keyvals[i+1] = nil
+					}
+				}
+				line := fmt.Sprintf("%s %v", msg, keyvals)
+				lines = append(lines, line)
+			})
+			retr := retrier.NewRetrier(1, retrier.ConstantDelay(time.Millisecond*5),
+				retrier.WithName(test.Retrier),
+				WithLogger(logger),
+			)
+			if test.Ledger != nil {
+				retr = retr.WithOptions(retrier.WithLedger(
+					func(ctx context.Context, rec retrier.AttemptRecord) error {
+						return test.Ledger
+					},
+					retrier.LedgerContinue,
+				))
+			}
+
+			count := 0
+			retr.Run(func() (error, bool) {
+				count++
+				if count <= test.Fails {
+					return fmt.Errorf("error"), true
+				}
+				return nil, false
+			})
+
+			assert.Equal(t, test.Lines, lines)
+		})
+	}
+}
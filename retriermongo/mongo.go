@@ -0,0 +1,124 @@
+// Package retriermongo retries MongoDB transactions of the mongo driver with a
+// retrier, following the error labels that the server attaches to errors.
+package retriermongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Error labels that decide how transactions are retried.
+const (
+	// TransientTransactionError labels errors after which the whole
+	// transaction can be run again.
+	TransientTransactionError = "TransientTransactionError"
+
+	// UnknownTransactionCommitResult labels errors of commits that may or may
+	// not have been applied, after which the commit can be sent again.
+	UnknownTransactionCommitResult = "UnknownTransactionCommitResult"
+
+	// RetryableWriteError labels errors after which a write can be sent
+	// again.
+	RetryableWriteError = "RetryableWriteError"
+)
+
+// HasLabel reports whether an error or any of the errors it wraps has an
+// error label.
+func HasLabel(err error, label string) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if le, ok := err.(mongo.LabeledError); ok && le.HasErrorLabel(label) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transient reports whether an error of a transaction is labeled as a
+// TransientTransactionError, so the whole transaction can be run again.
+func Transient(err error) bool {
+	return HasLabel(err, TransientTransactionError)
+}
+
+// UnknownCommitResult reports whether an error of a commit is labeled as an
+// UnknownTransactionCommitResult, so the commit can be sent again. Commits
+// that exceeded their max time are not retried.
+func UnknownCommitResult(err error) bool {
+	var cerr mongo.CommandError
+	if errors.As(err, &cerr) && cerr.IsMaxTimeMSExpiredError() {
+		return false
+	}
+	return HasLabel(err, UnknownTransactionCommitResult)
+}
+
+// Retryable reports whether an error of an operation outside of transactions
+// is transient, so the operation can be retried. It recognizes retryable
+// writes, transient transaction errors and network errors.
+func Retryable(err error) bool {
+	return HasLabel(err, RetryableWriteError) ||
+		Transient(err) ||
+		mongo.IsNetworkError(err)
+}
+
+// RunTx runs a function in a transaction of a session, and retries it with
+// the retrier the way the transactions specification prescribes. If the
+// function or the commit fails with a TransientTransactionError, the
+// transaction is aborted and run again. If the commit fails with an
+// UnknownTransactionCommitResult, only the commit is retried, within the same
+// run of the retrier, with the delays and the limit of retries of the
+// retrier. When the commit finally fails, the transaction is aborted. The
+// function must use the session context for the operations of the
+// transaction, and may be run more than once.
+func RunTx(
+	ctx context.Context,
+	r *retrier.Retrier,
+	sess mongo.Session,
+	fn func(ctx mongo.SessionContext) error,
+	opts ...*options.TransactionOptions,
+) error {
+	return r.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		if err := sess.StartTransaction(opts...); err != nil {
+			return err, false
+		}
+
+		if err := fn(mongo.NewSessionContext(ctx, sess)); err != nil {
+			// The transaction is aborted even if the context is done, so that
+			// the server releases its resources.
+			sess.AbortTransaction(context.Background())
+			return err, Transient(err)
+		}
+
+		if err := commit(ctx, r, sess); err != nil {
+			sess.AbortTransaction(context.Background())
+			return err, Transient(err)
+		}
+		return nil, false
+	})
+}
+
+// commit commits the transaction of a session, and sends the commit again
+// while it fails with an UnknownTransactionCommitResult, waiting the delays of
+// the retrier up to its limit of retries. The commit is retried without a run
+// of its own, so the transaction counts as a single run of the retrier.
+func commit(ctx context.Context, r *retrier.Retrier, sess mongo.Session) error {
+	for retries := 0; ; retries++ {
+		err := sess.CommitTransaction(ctx)
+		if err == nil || !UnknownCommitResult(err) {
+			return err
+		} else if r.Max() != retrier.NoLimit && retries >= r.Max() {
+			return err
+		}
+
+		t := time.NewTimer(r.NextDelay(retries, err))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
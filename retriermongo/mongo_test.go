@@ -0,0 +1,199 @@
+package retriermongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeSession is a session that records the calls of transactions, and fails
+// commits with errors from a list.
+type fakeSession struct {
+	mongo.Session
+	calls      []string
+	commitErrs []error
+}
+
+func (s *fakeSession) StartTransaction(...*options.TransactionOptions) error {
+	s.calls = append(s.calls, "start")
+	return nil
+}
+
+func (s *fakeSession) AbortTransaction(context.Context) error {
+	s.calls = append(s.calls, "abort")
+	return nil
+}
+
+func (s *fakeSession) CommitTransaction(context.Context) error {
+	s.calls = append(s.calls, "commit")
+	if len(s.commitErrs) == 0 {
+		return nil
+	}
+	err := s.commitErrs[0]
+	s.commitErrs = s.commitErrs[1:]
+	return err
+}
+
+// labeled returns a command error with error labels.
+func labeled(labels ...string) error {
+	return mongo.CommandError{Code: 112, Message: "error", Labels: labels}
+}
+
+// TestClassifiers tests if errors are classified by their labels
+func TestClassifiers(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Err           error
+		Transient     bool
+		UnknownCommit bool
+		Retryable     bool
+	}{
+		{
+			Name: "Nil",
+			Err:  nil,
+		},
+		{
+			Name:      "Transient transaction error",
+			Err:       labeled(TransientTransactionError),
+			Transient: true,
+			Retryable: true,
+		},
+		{
+			Name:          "Unknown commit result",
+			Err:           labeled(UnknownTransactionCommitResult),
+			UnknownCommit: true,
+		},
+		{
+			Name: "Unknown commit result after max time",
+			Err: mongo.CommandError{
+				Code:   50,
+				Name:   "MaxTimeMSExpired",
+				Labels: []string{UnknownTransactionCommitResult},
+			},
+			UnknownCommit: false,
+		},
+		{
+			Name:      "Wrapped retryable write",
+			Err:       fmt.Errorf("insert: %w", labeled(RetryableWriteError)),
+			Retryable: true,
+		},
+		{
+			Name:      "Network error",
+			Err:       labeled("NetworkError"),
+			Retryable: true,
+		},
+		{
+			Name: "Other error",
+			Err:  errors.New("error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Transient, Transient(test.Err))
+			assert.Equal(t, test.UnknownCommit, UnknownCommitResult(test.Err))
+			assert.Equal(t, test.Retryable, Retryable(test.Err))
+		})
+	}
+}
+
+// TestRunTx tests if transactions are run again after transient errors, only
+// commits are retried after unknown commit results, and every transaction is
+// a single run of the retrier
+func TestRunTx(t *testing.T) {
+	tests := []struct {
+		Name       string
+		FnErrs     []error
+		CommitErrs []error
+		Calls      []string
+		Err        error
+	}{
+		{
+			Name:  "Success",
+			Calls: []string{"start", "fn", "commit"},
+		},
+		{
+			Name:   "Transient error in transaction",
+			FnErrs: []error{labeled(TransientTransactionError)},
+			Calls:  []string{"start", "fn", "abort", "start", "fn", "commit"},
+		},
+		{
+			Name:   "Other error in transaction",
+			FnErrs: []error{errors.New("duplicate key")},
+			Calls:  []string{"start", "fn", "abort"},
+			Err:    errors.New("duplicate key"),
+		},
+		{
+			Name:       "Unknown commit result",
+			CommitErrs: []error{labeled(UnknownTransactionCommitResult)},
+			Calls:      []string{"start", "fn", "commit", "commit"},
+		},
+		{
+			Name:       "Transient error in commit",
+			CommitErrs: []error{labeled(TransientTransactionError)},
+			Calls:      []string{"start", "fn", "commit", "abort", "start", "fn", "commit"},
+		},
+		{
+			Name: "Unknown commit result after max retries",
+			CommitErrs: []error{
+				labeled(UnknownTransactionCommitResult),
+				labeled(UnknownTransactionCommitResult),
+				labeled(UnknownTransactionCommitResult),
+				labeled(UnknownTransactionCommitResult),
+			},
+			Calls: []string{"start", "fn", "commit", "commit", "commit", "commit", "abort"},
+			Err:   labeled(UnknownTransactionCommitResult),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			sess := &fakeSession{commitErrs: test.CommitErrs}
+			fn := func(ctx mongo.SessionContext) error {
+				sess.calls = append(sess.calls, "fn")
+				if len(test.FnErrs) == 0 {
+					return nil
+				}
+				err := test.FnErrs[0]
+				test.FnErrs = test.FnErrs[1:]
+				return err
+			}
+
+			retr := retrier.NewRetrier(3, retrier.NoDelay())
+			err := RunTx(context.Background(), retr, sess, fn)
+
+			if test.Err != nil {
+				assert.EqualError(t, err, test.Err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.Calls, sess.calls)
+			assert.Equal(t, int64(1), retr.Stats().Runs)
+		})
+	}
+}
+
+// TestRunTxDrain tests if a transaction that is in flight when the retrier is
+// drained is still committed
+func TestRunTxDrain(t *testing.T) {
+	retr := retrier.NewRetrier(3, retrier.NoDelay())
+	sess := &fakeSession{
+		commitErrs: []error{labeled(UnknownTransactionCommitResult)},
+	}
+	fn := func(ctx mongo.SessionContext) error {
+		sess.calls = append(sess.calls, "fn")
+		retr.Drain()
+		return nil
+	}
+
+	err := RunTx(context.Background(), retr, sess, fn)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start", "fn", "commit", "commit"}, sess.calls)
+}
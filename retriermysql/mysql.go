@@ -0,0 +1,35 @@
+// Package retriermysql classifies the errors of MySQL and MariaDB databases
+// accessed with go-sql-driver/mysql for retrying.
+package retriermysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	// ErLockWaitTimeout is the error number of lock wait timeouts.
+	ErLockWaitTimeout = 1205
+
+	// ErLockDeadlock is the error number of deadlocks.
+	ErLockDeadlock = 1213
+)
+
+// Retryable reports whether an error of go-sql-driver/mysql is transient, so
+// the operation that failed with it can be retried. It recognizes deadlocks
+// (1213), lock wait timeouts (1205), and connections that were reset or
+// closed. It can be used with retriersql.WithTransient or
+// retrier.WithRetryIf.
+func Retryable(err error) bool {
+	var merr *mysql.MySQLError
+	if errors.As(err, &merr) {
+		return merr.Number == ErLockDeadlock || merr.Number == ErLockWaitTimeout
+	}
+
+	return errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,78 @@
+package retriermysql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRetryable tests if transient MySQL errors are recognized by their error
+// numbers, and reset connections are retried
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Error     error
+		Retryable bool
+	}{
+		{
+			Name:      "Deadlock",
+			Error:     &mysql.MySQLError{Number: 1213},
+			Retryable: true,
+		},
+		{
+			Name:      "Lock wait timeout",
+			Error:     &mysql.MySQLError{Number: 1205},
+			Retryable: true,
+		},
+		{
+			Name:      "Wrapped deadlock",
+			Error:     fmt.Errorf("update failed: %w", &mysql.MySQLError{Number: 1213}),
+			Retryable: true,
+		},
+		{
+			Name:      "Duplicate entry",
+			Error:     &mysql.MySQLError{Number: 1062},
+			Retryable: false,
+		},
+		{
+			Name:      "Invalid connection",
+			Error:     mysql.ErrInvalidConn,
+			Retryable: true,
+		},
+		{
+			Name:      "Bad connection",
+			Error:     driver.ErrBadConn,
+			Retryable: true,
+		},
+		{
+			Name: "Connection reset",
+			Error: &net.OpError{
+				Op:  "read",
+				Err: os.NewSyscallError("read", syscall.ECONNRESET),
+			},
+			Retryable: true,
+		},
+		{
+			Name:      "Other error",
+			Error:     fmt.Errorf("error"),
+			Retryable: false,
+		},
+		{
+			Name:      "No error",
+			Error:     nil,
+			Retryable: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Retryable, Retryable(test.Error))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package retriernats retries the requests and publishes of NATS and
+// JetStream clients with a retrier.
+package retriernats
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Soreing/retrier"
+	"github.com/nats-io/nats.go"
+)
+
+// Requester is the interface of the clients that send requests, which is
+// implemented by *nats.Conn.
+type Requester interface {
+	RequestWithContext(
+		ctx context.Context,
+		subj string,
+		data []byte,
+	) (*nats.Msg, error)
+}
+
+// Publisher is the interface of the clients that publish messages, which is
+// implemented by *nats.Conn.
+type Publisher interface {
+	Publish(subj string, data []byte) error
+}
+
+// JetStreamPublisher is the interface of the clients that publish messages to
+// streams and wait for acknowledgements, which is implemented by
+// nats.JetStreamContext.
+type JetStreamPublisher interface {
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+}
+
+// Retryable reports whether an error of a request or a publish is transient,
+// so it can be sent again. It recognizes requests without responders, timed
+// out requests and acknowledgements, and connections that are reconnecting
+// or whose reconnect buffer is full.
+func Retryable(err error) bool {
+	return errors.Is(err, nats.ErrNoResponders) ||
+		errors.Is(err, nats.ErrTimeout) ||
+		errors.Is(err, nats.ErrNoStreamResponse) ||
+		errors.Is(err, nats.ErrConnectionReconnecting) ||
+		errors.Is(err, nats.ErrReconnectBufExceeded) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// Request sends a request and waits for the response, and retries it
+// according to the retrier when there are no responders or it times out.
+// Each attempt waits for the response until the context of the attempt is
+// done, so the retrier should have an attempt timeout.
+func Request(
+	ctx context.Context,
+	r *retrier.Retrier,
+	c Requester,
+	subj string,
+	data []byte,
+) (*nats.Msg, error) {
+	return retrier.Do(ctx, r,
+		func(ctx context.Context) (*nats.Msg, error, bool) {
+			msg, err := c.RequestWithContext(ctx, subj, data)
+			return msg, err, Retryable(err)
+		},
+	)
+}
+
+// Publish publishes a message, and retries it according to the retrier when
+// the connection is reconnecting and its buffer is full.
+func Publish(
+	ctx context.Context,
+	r *retrier.Retrier,
+	c Publisher,
+	subj string,
+	data []byte,
+) error {
+	return r.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		err := c.Publish(subj, data)
+		return err, Retryable(err)
+	})
+}
+
+// PublishJetStream publishes a message to a stream and waits for the
+// acknowledgement, and retries it according to the retrier when the stream
+// does not respond or the acknowledgement times out. The context of each
+// attempt is passed to the publish. Messages that may be published more than
+// once should have a message ID, so the stream discards duplicates.
+func PublishJetStream(
+	ctx context.Context,
+	r *retrier.Retrier,
+	js JetStreamPublisher,
+	msg *nats.Msg,
+	opts ...nats.PubOpt,
+) (*nats.PubAck, error) {
+	return retrier.Do(ctx, r,
+		func(ctx context.Context) (*nats.PubAck, error, bool) {
+			aopts := append([]nats.PubOpt{nats.Context(ctx)}, opts...)
+			ack, err := js.PublishMsg(msg, aopts...)
+			return ack, err, Retryable(err)
+		},
+	)
+}
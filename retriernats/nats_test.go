@@ -0,0 +1,188 @@
+package retriernats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClient is a client that fails with errors from a list before it
+// succeeds, and counts its attempts.
+type fakeClient struct {
+	errs     []error
+	attempts int
+	deadline bool
+}
+
+// next returns the error of the next attempt.
+func (c *fakeClient) next() error {
+	c.attempts++
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+func (c *fakeClient) RequestWithContext(
+	ctx context.Context,
+	subj string,
+	data []byte,
+) (*nats.Msg, error) {
+	_, c.deadline = ctx.Deadline()
+	if err := c.next(); err != nil {
+		return nil, err
+	}
+	return &nats.Msg{Subject: subj, Data: data}, nil
+}
+
+func (c *fakeClient) Publish(subj string, data []byte) error {
+	return c.next()
+}
+
+func (c *fakeClient) PublishMsg(
+	m *nats.Msg,
+	opts ...nats.PubOpt,
+) (*nats.PubAck, error) {
+	if err := c.next(); err != nil {
+		return nil, err
+	}
+	return &nats.PubAck{Stream: "events", Sequence: uint64(c.attempts)}, nil
+}
+
+// TestRetryable tests if errors of missing responders, timeouts and
+// reconnecting connections are retryable, while other errors are not
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Err       error
+		Retryable bool
+	}{
+		{Name: "Nil", Err: nil, Retryable: false},
+		{Name: "No responders", Err: nats.ErrNoResponders, Retryable: true},
+		{Name: "Timeout", Err: nats.ErrTimeout, Retryable: true},
+		{Name: "No stream response", Err: nats.ErrNoStreamResponse, Retryable: true},
+		{Name: "Reconnecting", Err: nats.ErrConnectionReconnecting, Retryable: true},
+		{Name: "Deadline exceeded", Err: context.DeadlineExceeded, Retryable: true},
+		{Name: "Wrapped", Err: fmt.Errorf("request: %w", nats.ErrTimeout), Retryable: true},
+		{Name: "Bad subject", Err: nats.ErrBadSubject, Retryable: false},
+		{Name: "Other error", Err: errors.New("error"), Retryable: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Retryable, Retryable(test.Err))
+		})
+	}
+}
+
+// TestRequest tests if requests are retried on retryable errors, each with
+// the deadline of the attempt timeout, until they succeed or fail otherwise
+func TestRequest(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Errs     []error
+		Attempts int
+		Err      string
+	}{
+		{
+			Name:     "Success",
+			Errs:     nil,
+			Attempts: 1,
+		},
+		{
+			Name:     "No responders then success",
+			Errs:     []error{nats.ErrNoResponders, nats.ErrTimeout},
+			Attempts: 3,
+		},
+		{
+			Name:     "Fatal error",
+			Errs:     []error{nats.ErrNoResponders, nats.ErrBadSubject},
+			Attempts: 2,
+			Err:      nats.ErrBadSubject.Error(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			c := &fakeClient{errs: test.Errs}
+			retr := retrier.NewRetrier(
+				5, retrier.NoDelay(),
+				retrier.WithAttemptTimeout(time.Second),
+			)
+
+			msg, err := Request(context.Background(), retr, c, "svc", []byte("req"))
+
+			if test.Err != "" {
+				assert.EqualError(t, err, test.Err)
+				assert.Nil(t, msg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("req"), msg.Data)
+			}
+			assert.Equal(t, test.Attempts, c.attempts)
+			assert.True(t, c.deadline)
+		})
+	}
+}
+
+// TestPublish tests if publishes are retried while the connection is
+// reconnecting
+func TestPublish(t *testing.T) {
+	c := &fakeClient{errs: []error{nats.ErrReconnectBufExceeded}}
+	retr := retrier.NewRetrier(5, retrier.NoDelay())
+
+	err := Publish(context.Background(), retr, c, "events", []byte("msg"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.attempts)
+}
+
+// TestPublishJetStream tests if JetStream publishes are retried until they
+// are acknowledged, and fail when the retrier runs out of retries
+func TestPublishJetStream(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Errs     []error
+		Attempts int
+		Err      string
+	}{
+		{
+			Name:     "Acknowledged after retries",
+			Errs:     []error{nats.ErrNoStreamResponse, nats.ErrTimeout},
+			Attempts: 3,
+		},
+		{
+			Name:     "Retries run out",
+			Errs:     []error{nats.ErrTimeout, nats.ErrTimeout, nats.ErrTimeout},
+			Attempts: 3,
+			Err:      "failed after max retries: nats: timeout",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			c := &fakeClient{errs: test.Errs}
+			retr := retrier.NewRetrier(2, retrier.NoDelay())
+
+			msg := nats.NewMsg("events")
+			msg.Header.Set(nats.MsgIdHdr, "id")
+			ack, err := PublishJetStream(context.Background(), retr, c, msg)
+
+			if test.Err != "" {
+				assert.EqualError(t, err, test.Err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, uint64(test.Attempts), ack.Sequence)
+			}
+			assert.Equal(t, test.Attempts, c.attempts)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package retrierotel instruments retriers with OpenTelemetry.
+package retrierotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// instrumentation is the name of the instrumentation library.
+const instrumentation = "github.com/Soreing/retrier/retrierotel"
+
+// NameKey is the attribute key of the name of the retrier.
+const NameKey = attribute.Key("retrier.name")
+
+// Metrics records the metrics of retriers with OpenTelemetry instruments,
+// with the name of the retrier as an attribute. It is added to retriers as an
+// observer with retrier.WithObserver. The same metrics can observe many
+// retriers, and are safe for concurrent use.
+//
+// The recorded instruments are:
+//   - retrier.attempts counts the attempts of tasks.
+//   - retrier.retries counts the retries of tasks.
+//   - retrier.exhausted counts the runs that the retrier gave up on.
+//   - retrier.sleep is a histogram of the delays before retries in seconds.
+type Metrics struct {
+	attempts  metric.Int64Counter
+	retries   metric.Int64Counter
+	exhausted metric.Int64Counter
+	sleep     metric.Float64Histogram
+}
+
+// MetricsOption configures metrics.
+type MetricsOption func(*metricsConfig)
+
+// metricsConfig is the configuration of metrics.
+type metricsConfig struct {
+	provider metric.MeterProvider
+}
+
+// WithMeterProvider sets the meter provider that creates the instruments,
+// replacing the global meter provider.
+func WithMeterProvider(mp metric.MeterProvider) MetricsOption {
+	return func(c *metricsConfig) {
+		c.provider = mp
+	}
+}
+
+// NewMetrics creates the instruments of the metrics of retriers with the
+// meter provider.
+func NewMetrics(opts ...MetricsOption) (*Metrics, error) {
+	cfg := &metricsConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.provider == nil {
+		cfg.provider = otel.GetMeterProvider()
+	}
+
+	meter := cfg.provider.Meter(instrumentation)
+	m := &Metrics{}
+	var err error
+	if m.attempts, err = meter.Int64Counter(
+		"retrier.attempts",
+		metric.WithDescription("Number of attempts of tasks."),
+	); err != nil {
+		return nil, err
+	}
+	if m.retries, err = meter.Int64Counter(
+		"retrier.retries",
+		metric.WithDescription("Number of retries of tasks."),
+	); err != nil {
+		return nil, err
+	}
+	if m.exhausted, err = meter.Int64Counter(
+		"retrier.exhausted",
+		metric.WithDescription("Number of runs that the retrier gave up on."),
+	); err != nil {
+		return nil, err
+	}
+	if m.sleep, err = meter.Float64Histogram(
+		"retrier.sleep",
+		metric.WithDescription("Delays before retries of tasks."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ObserveAttempt counts an attempt of a task.
+func (m *Metrics) ObserveAttempt(name string, rec retrier.AttemptRecord) {
+	m.attempts.Add(context.Background(), 1, nameAttr(name))
+}
+
+// ObserveRetry counts a retry of a task and records its delay.
+func (m *Metrics) ObserveRetry(
+	name string,
+	attempt int,
+	err error,
+	delay time.Duration,
+) {
+	m.retries.Add(context.Background(), 1, nameAttr(name))
+	m.sleep.Record(context.Background(), delay.Seconds(), nameAttr(name))
+}
+
+// ObserveRun counts the runs that the retrier gave up on.
+func (m *Metrics) ObserveRun(name string, res retrier.Result, err error) {
+	if _, ok := err.(*retrier.ExhaustedError); ok {
+		m.exhausted.Add(context.Background(), 1, nameAttr(name))
+	}
+}
+
+// nameAttr returns the measurement option with the name of the retrier.
+func nameAttr(name string) metric.MeasurementOption {
+	return metric.WithAttributes(NameKey.String(name))
+}
@@ -0,0 +1,70 @@
+package retrierotel
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// TestMetrics tests if the attempts, retries, exhausted runs and delays of
+// retriers are recorded with the names of the retriers
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	m, err := NewMetrics(WithMeterProvider(mp))
+	assert.NoError(t, err)
+
+	users := retrier.NewRetrier(2, retrier.ConstantDelay(time.Millisecond*5),
+		retrier.WithName("users"),
+		retrier.WithObserver(m),
+	)
+	orders := retrier.NewRetrier(2, retrier.NoDelay(),
+		retrier.WithName("orders"),
+		retrier.WithObserver(m),
+	)
+
+	users.Run(func() (error, bool) {
+		return fmt.Errorf("error"), true
+	})
+	count := 0
+	orders.Run(func() (error, bool) {
+		count++
+		return nil, count < 2
+	})
+
+	data := metricdata.ResourceMetrics{}
+	assert.NoError(t, reader.Collect(context.Background(), &data))
+
+	sums := map[string]map[string]int64{}
+	sleeps := map[string]uint64{}
+	for _, sm := range data.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			switch agg := metric.Data.(type) {
+			case metricdata.Sum[int64]:
+				sums[metric.Name] = map[string]int64{}
+				for _, dp := range agg.DataPoints {
+					name, _ := dp.Attributes.Value(NameKey)
+					sums[metric.Name][name.AsString()] = dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range agg.DataPoints {
+					name, _ := dp.Attributes.Value(NameKey)
+					sleeps[name.AsString()] = dp.Count
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]map[string]int64{
+		"retrier.attempts":  {"users": 3, "orders": 2},
+		"retrier.retries":   {"users": 2, "orders": 1},
+		"retrier.exhausted": {"users": 1},
+	}, sums)
+	assert.Equal(t, map[string]uint64{"users": 2, "orders": 1}, sleeps)
+}
@@ -0,0 +1,86 @@
+package retrierotel
+
+import (
+	"context"
+
+	"github.com/Soreing/retrier"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys of the spans of attempts.
+const (
+	// AttemptKey is the attribute key of the number of the attempt.
+	AttemptKey = attribute.Key("retrier.attempt")
+
+	// DelayKey is the attribute key of the delay slept before the attempt in
+	// seconds.
+	DelayKey = attribute.Key("retrier.delay")
+)
+
+// TracingOption configures tracing.
+type TracingOption func(*tracingConfig)
+
+// tracingConfig is the configuration of tracing.
+type tracingConfig struct {
+	provider trace.TracerProvider
+	spanName string
+}
+
+// WithTracerProvider sets the tracer provider that creates the spans,
+// replacing the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) TracingOption {
+	return func(c *tracingConfig) {
+		c.provider = tp
+	}
+}
+
+// WithSpanName sets the name of the spans of attempts, replacing the default
+// name "retrier.attempt".
+func WithSpanName(name string) TracingOption {
+	return func(c *tracingConfig) {
+		c.spanName = name
+	}
+}
+
+// WithTracing returns an option of retriers that creates a span for every
+// attempt of a task, as a child of the span in the context of the run. The
+// spans have the number of the attempt, the delay slept before it and the
+// name of the retrier as attributes, and the error of a failed attempt is
+// recorded on its span with an error status.
+func WithTracing(opts ...TracingOption) retrier.Option {
+	cfg := &tracingConfig{spanName: "retrier.attempt"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.provider == nil {
+		cfg.provider = otel.GetTracerProvider()
+	}
+	tracer := cfg.provider.Tracer(instrumentation)
+
+	return retrier.WithInterceptor(func(
+		ctx context.Context,
+		next func(ctx context.Context) (error, bool),
+	) (error, bool) {
+		att, _ := retrier.AttemptFromContext(ctx)
+		attrs := []attribute.KeyValue{
+			AttemptKey.Int(att.Number),
+			DelayKey.Float64(att.Delay.Seconds()),
+		}
+		if name, ok := retrier.NameFromContext(ctx); ok {
+			attrs = append(attrs, NameKey.String(name))
+		}
+
+		ctx, span := tracer.Start(ctx, cfg.spanName, trace.WithAttributes(attrs...))
+		defer span.End()
+
+		err, retry := next(ctx)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err, retry
+	})
+}
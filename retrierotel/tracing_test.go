@@ -0,0 +1,61 @@
+package retrierotel
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestTracing tests if a span is created for every attempt as a child of the
+// span of the caller, with the attempt number, the delay and the error status
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	retr := retrier.NewRetrier(2, retrier.ConstantDelay(time.Millisecond*5),
+		retrier.WithName("users"),
+		WithTracing(WithTracerProvider(tp)),
+	)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	count := 0
+	err := retr.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		count++
+		if count < 3 {
+			return fmt.Errorf("error %d", count), true
+		}
+		return nil, false
+	})
+	parent.End()
+	assert.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	if !assert.Len(t, spans, 4) {
+		return
+	}
+	delays := []float64{0, 0.005, 0.005}
+	statuses := []codes.Code{codes.Error, codes.Error, codes.Unset}
+	for i, span := range spans[:3] {
+		assert.Equal(t, "retrier.attempt", span.Name)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.Equal(t, statuses[i], span.Status.Code)
+
+		attrs := attribute.NewSet(span.Attributes...)
+		number, _ := attrs.Value(AttemptKey)
+		delay, _ := attrs.Value(DelayKey)
+		name, _ := attrs.Value(NameKey)
+		assert.Equal(t, int64(i+1), number.AsInt64())
+		assert.Equal(t, delays[i], delay.AsFloat64())
+		assert.Equal(t, "users", name.AsString())
+	}
+	assert.Equal(t, "error 1", spans[0].Status.Description)
+	assert.Equal(t, "parent", spans[3].Name)
+}
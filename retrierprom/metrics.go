@@ -0,0 +1,133 @@
+// Package retrierprom records the attempts and retries of retriers as
+// Prometheus metrics.
+package retrierprom
+
+import (
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultSleepBuckets are the default buckets in seconds of the histogram of
+// delays before retries.
+var DefaultSleepBuckets = []float64{
+	0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60,
+}
+
+// Metrics is a Prometheus collector of the metrics of retriers, labeled by the
+// name of the retrier. It is added to retriers as an observer with
+// retrier.WithObserver, and registered with a Prometheus registry. The same
+// metrics can observe many retriers, and are safe for concurrent use.
+//
+// The collected metrics are:
+//   - retrier_attempts_total counts the attempts of tasks.
+//   - retrier_retries_total counts the retries of tasks.
+//   - retrier_exhausted_total counts the runs that the retrier gave up on.
+//   - retrier_sleep_seconds is a histogram of the delays before retries.
+type Metrics struct {
+	attempts  *prometheus.CounterVec
+	retries   *prometheus.CounterVec
+	exhausted *prometheus.CounterVec
+	sleep     *prometheus.HistogramVec
+}
+
+// Option configures metrics.
+type Option func(*config)
+
+// config is the configuration of metrics.
+type config struct {
+	namespace string
+	buckets   []float64
+}
+
+// WithNamespace sets the namespace of the metrics, which prefixes their names.
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithSleepBuckets sets the buckets in seconds of the histogram of delays
+// before retries, replacing DefaultSleepBuckets.
+func WithSleepBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// NewMetrics creates the metrics of retriers.
+func NewMetrics(opts ...Option) *Metrics {
+	cfg := &config{buckets: DefaultSleepBuckets}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	labels := []string{"name"}
+	return &Metrics{
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "retrier",
+			Name:      "attempts_total",
+			Help:      "Number of attempts of tasks.",
+		}, labels),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "retrier",
+			Name:      "retries_total",
+			Help:      "Number of retries of tasks.",
+		}, labels),
+		exhausted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "retrier",
+			Name:      "exhausted_total",
+			Help:      "Number of runs that the retrier gave up on.",
+		}, labels),
+		sleep: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Subsystem: "retrier",
+			Name:      "sleep_seconds",
+			Help:      "Delays before retries of tasks in seconds.",
+			Buckets:   cfg.buckets,
+		}, labels),
+	}
+}
+
+// Describe sends the descriptors of the metrics to a channel.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.attempts.Describe(ch)
+	m.retries.Describe(ch)
+	m.exhausted.Describe(ch)
+	m.sleep.Describe(ch)
+}
+
+// Collect sends the values of the metrics to a channel.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.attempts.Collect(ch)
+	m.retries.Collect(ch)
+	m.exhausted.Collect(ch)
+	m.sleep.Collect(ch)
+}
+
+// ObserveAttempt counts an attempt of a task.
+func (m *Metrics) ObserveAttempt(name string, rec retrier.AttemptRecord) {
+	m.attempts.WithLabelValues(name).Inc()
+}
+
+// ObserveRetry counts a retry of a task and records its delay.
+func (m *Metrics) ObserveRetry(
+	name string,
+	attempt int,
+	err error,
+	delay time.Duration,
+) {
+	m.retries.WithLabelValues(name).Inc()
+	m.sleep.WithLabelValues(name).Observe(delay.Seconds())
+}
+
+// ObserveRun counts the runs that the retrier gave up on.
+func (m *Metrics) ObserveRun(name string, res retrier.Result, err error) {
+	if _, ok := err.(*retrier.ExhaustedError); ok {
+		m.exhausted.WithLabelValues(name).Inc()
+	}
+}
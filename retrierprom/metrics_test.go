@@ -0,0 +1,67 @@
+package retrierprom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetrics tests if the attempts, retries, exhausted runs and delays of
+// retriers are collected with the names of the retriers
+func TestMetrics(t *testing.T) {
+	m := NewMetrics(WithSleepBuckets(0.001, 0.01))
+	reg := prometheus.NewPedanticRegistry()
+	assert.NoError(t, reg.Register(m))
+
+	users := retrier.NewRetrier(2, retrier.ConstantDelay(time.Millisecond*5),
+		retrier.WithName("users"),
+		retrier.WithObserver(m),
+	)
+	orders := retrier.NewRetrier(2, retrier.NoDelay(),
+		retrier.WithName("orders"),
+		retrier.WithObserver(m),
+	)
+
+	users.Run(func() (error, bool) {
+		return fmt.Errorf("error"), true
+	})
+	count := 0
+	orders.Run(func() (error, bool) {
+		count++
+		return nil, count < 2
+	})
+
+	expected := `
+# HELP retrier_attempts_total Number of attempts of tasks.
+# TYPE retrier_attempts_total counter
+retrier_attempts_total{name="orders"} 2
+retrier_attempts_total{name="users"} 3
+# HELP retrier_exhausted_total Number of runs that the retrier gave up on.
+# TYPE retrier_exhausted_total counter
+retrier_exhausted_total{name="users"} 1
+# HELP retrier_retries_total Number of retries of tasks.
+# TYPE retrier_retries_total counter
+retrier_retries_total{name="orders"} 1
+retrier_retries_total{name="users"} 2
+# HELP retrier_sleep_seconds Delays before retries of tasks in seconds.
+# TYPE retrier_sleep_seconds histogram
+retrier_sleep_seconds_bucket{name="orders",le="0.001"} 1
+retrier_sleep_seconds_bucket{name="orders",le="0.01"} 1
+retrier_sleep_seconds_bucket{name="orders",le="+Inf"} 1
+retrier_sleep_seconds_sum{name="orders"} 0
+retrier_sleep_seconds_count{name="orders"} 1
+retrier_sleep_seconds_bucket{name="users",le="0.001"} 0
+retrier_sleep_seconds_bucket{name="users",le="0.01"} 2
+retrier_sleep_seconds_bucket{name="users",le="+Inf"} 2
+retrier_sleep_seconds_sum{name="users"} 0.01
+retrier_sleep_seconds_count{name="users"} 2
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected))
+	assert.NoError(t, err)
+}
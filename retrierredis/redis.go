@@ -0,0 +1,122 @@
+// Package retrierredis retries the commands of go-redis clients with a
+// retrier when they fail with transient errors, such as failovers and broken
+// connections.
+package retrierredis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/Soreing/retrier"
+	"github.com/redis/go-redis/v9"
+)
+
+// transientPrefixes are the prefixes of the error replies of Redis servers
+// that are in a transient state, such as loading a dataset, failing over or
+// migrating slots.
+var transientPrefixes = []string{
+	"LOADING ",
+	"READONLY ",
+	"CLUSTERDOWN ",
+	"TRYAGAIN ",
+	"MASTERDOWN ",
+	"MOVED ",
+	"ASK ",
+}
+
+// Retryable reports whether an error of a go-redis command is transient, so
+// the command can be retried. It recognizes the replies of servers that are
+// loading, read only replicas, clusters that are down or migrating slots, and
+// network errors. redis.Nil and context errors are not retryable.
+func Retryable(err error) bool {
+	if err == nil || err == redis.Nil {
+		return false
+	} else if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var rerr redis.Error
+	if errors.As(err, &rerr) {
+		msg := rerr.Error()
+		for _, prefix := range transientPrefixes {
+			if strings.HasPrefix(msg, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var nerr net.Error
+	return errors.As(err, &nerr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// Hook is a go-redis hook that retries commands with a retrier when they fail
+// with transient errors. Pipelines and transactions are sent once, because
+// they may have been partially executed when they fail.
+//
+// Commands that fail with network errors may have been executed by the
+// server, so commands that are not idempotent, such as INCR, can be applied
+// more than once. The built in retries of the client should be disabled with
+// MaxRetries set to -1 when the hook is used.
+type Hook struct {
+	retr     *retrier.Retrier
+	classify func(error) bool
+}
+
+// Option configures a hook.
+type Option func(*Hook)
+
+// WithClassifier sets the classifier that decides whether a command that
+// failed with an error is retried, replacing Retryable.
+func WithClassifier(classify func(err error) bool) Option {
+	return func(h *Hook) {
+		h.classify = classify
+	}
+}
+
+// NewHook creates a hook that retries commands with a retrier. It is added to
+// a client with AddHook.
+func NewHook(r *retrier.Retrier, opts ...Option) *Hook {
+	h := &Hook{
+		retr:     r,
+		classify: Retryable,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// DialHook returns the next hook, so connections are dialed once for each
+// attempt of a command.
+func (h *Hook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook returns a hook that retries commands according to the retrier
+// when they fail with transient errors.
+func (h *Hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		return h.retr.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+			err := next(ctx, cmd)
+			return err, h.classify(err)
+		})
+	}
+}
+
+// ProcessPipelineHook returns the next hook, so pipelines and transactions
+// are not retried.
+func (h *Hook) ProcessPipelineHook(
+	next redis.ProcessPipelineHook,
+) redis.ProcessPipelineHook {
+	return next
+}
@@ -0,0 +1,108 @@
+package retrierredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/Soreing/retrier"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// replyError is an error reply of a Redis server.
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+
+func (replyError) RedisError() {}
+
+// TestRetryable tests if errors of transient server states and network
+// errors are retryable, while other errors are not
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Err       error
+		Retryable bool
+	}{
+		{Name: "Nil", Err: nil, Retryable: false},
+		{Name: "Redis nil", Err: redis.Nil, Retryable: false},
+		{Name: "Canceled", Err: context.Canceled, Retryable: false},
+		{Name: "Loading", Err: replyError("LOADING Redis is loading the dataset in memory"), Retryable: true},
+		{Name: "Read only", Err: replyError("READONLY You can't write against a read only replica."), Retryable: true},
+		{Name: "Cluster down", Err: replyError("CLUSTERDOWN The cluster is down"), Retryable: true},
+		{Name: "Moved", Err: replyError("MOVED 3999 127.0.0.1:6381"), Retryable: true},
+		{Name: "Ask", Err: replyError("ASK 3999 127.0.0.1:6381"), Retryable: true},
+		{Name: "Wrong type", Err: replyError("WRONGTYPE Operation against a key holding the wrong kind of value"), Retryable: false},
+		{Name: "Wrapped reply", Err: fmt.Errorf("get: %w", replyError("LOADING loading")), Retryable: true},
+		{Name: "EOF", Err: io.EOF, Retryable: true},
+		{Name: "Net error", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, Retryable: true},
+		{Name: "Connection reset", Err: fmt.Errorf("read: %w", syscall.ECONNRESET), Retryable: true},
+		{Name: "Other error", Err: errors.New("error"), Retryable: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Retryable, Retryable(test.Err))
+		})
+	}
+}
+
+// TestHook tests if the hook retries commands that fail with transient
+// errors until they succeed or fail with other errors
+func TestHook(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Opts     []Option
+		Errs     []error
+		Attempts int
+		Err      error
+	}{
+		{
+			Name:     "Success",
+			Errs:     []error{nil},
+			Attempts: 1,
+			Err:      nil,
+		},
+		{
+			Name:     "Transient then success",
+			Errs:     []error{replyError("LOADING loading"), io.EOF, nil},
+			Attempts: 3,
+			Err:      nil,
+		},
+		{
+			Name:     "Redis nil",
+			Errs:     []error{redis.Nil},
+			Attempts: 1,
+			Err:      redis.Nil,
+		},
+		{
+			Name:     "Custom classifier",
+			Opts:     []Option{WithClassifier(func(err error) bool { return err == redis.Nil })},
+			Errs:     []error{redis.Nil, io.EOF},
+			Attempts: 2,
+			Err:      io.EOF,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			hook := NewHook(retrier.NewRetrier(5, retrier.NoDelay()), test.Opts...)
+			attempts := 0
+			process := redis.Hook(hook).ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+				err := test.Errs[attempts]
+				attempts++
+				return err
+			})
+
+			err := process(context.Background(), redis.NewStringCmd(context.Background(), "get", "key"))
+
+			assert.Equal(t, test.Err, err)
+			assert.Equal(t, test.Attempts, attempts)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// Package retrierslog logs the retries and the outcomes of runs of retriers
+// with log/slog.
+package retrierslog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Soreing/retrier"
+	"github.com/Soreing/retrier/retrierlog"
+)
+
+// WithSlog returns an option of retriers that logs every retry with the
+// number of the attempt that failed, the delay before the next attempt and
+// the error, and the outcome of every run with the number of attempts, the
+// elapsed time and the error if the task did not succeed. The records are
+// logged at a level, and have the name of the retrier if it has one. The
+// fields are the same as those of retrierlog.WithLogger.
+func WithSlog(l *slog.Logger, level slog.Level) retrier.Option {
+	return retrierlog.WithLogger(retrierlog.LoggerFunc(
+		func(msg string, keyvals ...any) {
+			l.Log(context.Background(), level, msg, keyvals...)
+		},
+	))
+}
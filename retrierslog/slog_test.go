@@ -0,0 +1,87 @@
+package retrierslog
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWithSlog tests if retries and outcomes of runs are logged with
+// structured fields at the configured level
+func TestWithSlog(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Level slog.Level
+		Fails int
+		Lines []string
+	}{
+		{
+			Name:  "Success",
+			Level: slog.LevelInfo,
+			Fails: 1,
+			Lines: []string{
+				`level=INFO msg="retrying task" retrier=users attempt=1 delay=5ms error=error`,
+				`level=INFO msg="task succeeded" retrier=users attempts=2`,
+			},
+		},
+		{
+			Name:  "Exhausted",
+			Level: slog.LevelWarn,
+			Fails: 5,
+			Lines: []string{
+				`level=WARN msg="retrying task" retrier=users attempt=1 delay=5ms error=error`,
+				`level=WARN msg="task failed" retrier=users attempts=2`,
+			},
+		},
+		{
+			Name:  "Below handler level",
+			Level: slog.LevelDebug,
+			Fails: 1,
+			Lines: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			handler := slog.NewTextHandler(buf, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			})
+			retr := retrier.NewRetrier(1, retrier.ConstantDelay(time.Millisecond*5),
+				retrier.WithName("users"),
+				WithSlog(slog.New(handler), test.Level),
+			)
+
+			count := 0
+			retr.Run(func() (error, bool) {
+				count++
+				if count <= test.Fails {
+					return fmt.Errorf("error"), true
+				}
+				return nil, false
+			})
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if buf.Len() == 0 {
+				lines = []string{}
+			}
+			assert.Len(t, lines, len(test.Lines))
+			for i := range test.Lines {
+				if i < len(lines) {
+					assert.True(t, strings.HasPrefix(lines[i], test.Lines[i]), lines[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package retriersql
+
+import (
+	"errors"
+	"strings"
+)
+
+// sqlStateError is an error with a SQLSTATE code, which is implemented by the
+// errors of both lib/pq and pgx.
+type sqlStateError interface {
+	error
+	SQLState() string
+}
+
+// safeToRetryError is an error that reports whether it is safe to retry the
+// operation that failed with it, which is implemented by the errors of pgx
+// that occur before any data is sent to the server.
+type safeToRetryError interface {
+	error
+	SafeToRetry() bool
+}
+
+// PostgresRetryable reports whether an error of a PostgreSQL driver is
+// transient, so the operation that failed with it can be retried. It
+// recognizes serialization failures (40001), deadlocks (40P01), servers that
+// are starting up or shutting down (57P01, 57P02, 57P03), connection
+// exceptions (class 08), and errors of pgx that are safe to retry. It is
+// compatible with lib/pq and pgx through errors.As, and can be used with
+// WithTransient or retrier.WithRetryIf.
+func PostgresRetryable(err error) bool {
+	var serr sqlStateError
+	if errors.As(err, &serr) {
+		switch code := serr.SQLState(); {
+		case code == "40001", code == "40P01":
+			return true
+		case code == "57P01", code == "57P02", code == "57P03":
+			return true
+		case strings.HasPrefix(code, "08"):
+			return true
+		}
+	}
+
+	var rerr safeToRetryError
+	return errors.As(err, &rerr) && rerr.SafeToRetry()
+}
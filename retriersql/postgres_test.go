@@ -0,0 +1,103 @@
+package retriersql
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pgError is an error with a SQLSTATE code like the errors of PostgreSQL
+// drivers.
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string {
+	return "pg error " + e.code
+}
+
+func (e *pgError) SQLState() string {
+	return e.code
+}
+
+// connectError is an error that reports whether it is safe to retry like the
+// connection errors of pgx.
+type connectError struct {
+	safe bool
+}
+
+func (e *connectError) Error() string {
+	return "failed to connect"
+}
+
+func (e *connectError) SafeToRetry() bool {
+	return e.safe
+}
+
+// TestPostgresRetryable tests if transient PostgreSQL errors are recognized by
+// their SQLSTATE codes, including wrapped errors
+func TestPostgresRetryable(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Error     error
+		Retryable bool
+	}{
+		{
+			Name:      "Serialization failure",
+			Error:     &pgError{"40001"},
+			Retryable: true,
+		},
+		{
+			Name:      "Deadlock detected",
+			Error:     &pgError{"40P01"},
+			Retryable: true,
+		},
+		{
+			Name:      "Cannot connect now",
+			Error:     &pgError{"57P03"},
+			Retryable: true,
+		},
+		{
+			Name:      "Connection failure",
+			Error:     &pgError{"08006"},
+			Retryable: true,
+		},
+		{
+			Name:      "Wrapped serialization failure",
+			Error:     fmt.Errorf("update failed: %w", &pgError{"40001"}),
+			Retryable: true,
+		},
+		{
+			Name:      "Unique violation",
+			Error:     &pgError{"23505"},
+			Retryable: false,
+		},
+		{
+			Name:      "Safe to retry",
+			Error:     &connectError{safe: true},
+			Retryable: true,
+		},
+		{
+			Name:      "Not safe to retry",
+			Error:     &connectError{safe: false},
+			Retryable: false,
+		},
+		{
+			Name:      "Other error",
+			Error:     fmt.Errorf("error"),
+			Retryable: false,
+		},
+		{
+			Name:      "No error",
+			Error:     nil,
+			Retryable: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Retryable, PostgresRetryable(test.Error))
+		})
+	}
+}
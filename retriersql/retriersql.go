@@ -0,0 +1,156 @@
+// Package retriersql retries database/sql operations with a retrier when they
+// fail with transient errors, such as broken connections.
+package retriersql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/Soreing/retrier"
+)
+
+// Queryer is the interface of the database handles that can be wrapped, which
+// is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Option configures the retry behavior of database operations.
+type Option func(*config)
+
+// config is the configuration of the retry behavior of database operations.
+type config struct {
+	transient []func(error) bool
+	txOpts    *sql.TxOptions
+}
+
+// WithTransient adds a classifier of errors that are transient and retried,
+// in addition to driver.ErrBadConn.
+func WithTransient(classify func(err error) bool) Option {
+	return func(c *config) {
+		c.transient = append(c.transient, classify)
+	}
+}
+
+// WithTxOptions sets the options of the transactions started by RunTx, such as
+// the isolation level.
+func WithTxOptions(opts *sql.TxOptions) Option {
+	return func(c *config) {
+		c.txOpts = opts
+	}
+}
+
+// newConfig creates a configuration from options.
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// retryable reports whether an error is transient, so the operation that
+// failed with it is retried.
+func (c *config) retryable(err error) bool {
+	if err == nil {
+		return false
+	} else if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	for _, classify := range c.transient {
+		if classify(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// DB wraps a database handle to retry its operations with a retrier when they
+// fail with transient errors. It is safe for concurrent use if the handle and
+// the retrier are.
+type DB struct {
+	db   Queryer
+	retr *retrier.Retrier
+	cfg  *config
+}
+
+// New wraps a database handle to retry its operations with a retrier.
+func New(db Queryer, r *retrier.Retrier, opts ...Option) *DB {
+	return &DB{
+		db:   db,
+		retr: r,
+		cfg:  newConfig(opts),
+	}
+}
+
+// ExecContext executes a query without returning rows, and retries it when it
+// fails with a transient error.
+func (d *DB) ExecContext(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (sql.Result, error) {
+	var res sql.Result
+	err := d.retr.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		var err error
+		res, err = d.db.ExecContext(ctx, query, args...)
+		return err, d.cfg.retryable(err)
+	})
+	return res, err
+}
+
+// QueryContext executes a query that returns rows, and retries it when it
+// fails with a transient error. Errors that occur while iterating the rows are
+// not retried.
+func (d *DB) QueryContext(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (*sql.Rows, error) {
+	// Rows are read after the attempt, so the query uses the context of the
+	// run instead of the context of the attempt.
+	var rows *sql.Rows
+	err := d.retr.RunCtx(ctx, func(context.Context) (error, bool) {
+		var err error
+		rows, err = d.db.QueryContext(ctx, query, args...)
+		return err, d.cfg.retryable(err)
+	})
+	return rows, err
+}
+
+// QueryRowContext executes a query that returns at most one row, and retries
+// it when it fails with a transient error. The error of the last attempt is
+// returned by the Scan method of the row. If the retrier rejects the run
+// before the first attempt, such as after it was drained, the query is not
+// executed, and Scan returns context.Canceled, since a row can not carry other
+// errors. Use QueryContext to get the error of the retrier instead.
+func (d *DB) QueryRowContext(
+	ctx context.Context,
+	query string,
+	args ...any,
+) *sql.Row {
+	var row *sql.Row
+	d.retr.RunCtx(ctx, func(context.Context) (error, bool) {
+		row = d.db.QueryRowContext(ctx, query, args...)
+		err := row.Err()
+		return err, d.cfg.retryable(err)
+	})
+
+	// A row with an error can not be created outside of database/sql, so if
+	// the run stopped before the first attempt, the query is started with a
+	// done context, which reports the error of the context through the row
+	// without executing the query.
+	if row == nil {
+		if ctx.Err() == nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+			cancel()
+		}
+		row = d.db.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
@@ -0,0 +1,245 @@
+package retriersql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver is a database driver that fails calls with a script of errors,
+// and records the statements it executes.
+type fakeDriver struct {
+	mu    sync.Mutex
+	errs  []error
+	calls []string
+}
+
+// next records a statement and returns the next error of the script.
+func (d *fakeDriver) next(stmt string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.calls = append(d.calls, stmt)
+	if len(d.errs) == 0 {
+		return nil
+	}
+	err := d.errs[0]
+	d.errs = d.errs[1:]
+	return err
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{d}, nil
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{d}, nil
+}
+
+func (d *fakeDriver) Driver() driver.Driver {
+	return d
+}
+
+// fakeConn is a connection of the fake driver.
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	if err := c.d.next("BEGIN"); err != nil {
+		return nil, err
+	}
+	return &fakeTx{c.d}, nil
+}
+
+func (c *fakeConn) ExecContext(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+) (driver.Result, error) {
+	if err := c.d.next(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+) (driver.Rows, error) {
+	if err := c.d.next(query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{}, nil
+}
+
+// fakeTx is a transaction of the fake driver.
+type fakeTx struct {
+	d *fakeDriver
+}
+
+func (t *fakeTx) Commit() error {
+	return t.d.next("COMMIT")
+}
+
+func (t *fakeTx) Rollback() error {
+	return t.d.next("ROLLBACK")
+}
+
+// fakeRows are rows of the fake driver with a single value.
+type fakeRows struct {
+	done bool
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+// TestDB tests if database operations are retried when they fail with
+// transient errors, and returned when they fail with other errors
+func TestDB(t *testing.T) {
+	errTransient := fmt.Errorf("transient")
+	errFatal := fmt.Errorf("fatal")
+
+	tests := []struct {
+		Name  string
+		Errs  []error
+		Calls int
+		Error error
+	}{
+		{
+			Name:  "Success",
+			Errs:  []error{},
+			Calls: 1,
+			Error: nil,
+		},
+		{
+			Name:  "Transient errors are retried",
+			Errs:  []error{errTransient, errTransient},
+			Calls: 3,
+			Error: nil,
+		},
+		{
+			Name:  "Fatal error is returned",
+			Errs:  []error{errFatal},
+			Calls: 1,
+			Error: errFatal,
+		},
+		{
+			Name:  "Transient errors after max retries",
+			Errs:  []error{errTransient, errTransient, errTransient},
+			Calls: 3,
+			Error: errTransient,
+		},
+	}
+
+	ops := map[string]func(db *DB) error{
+		"Exec": func(db *DB) error {
+			_, err := db.ExecContext(context.TODO(), "UPDATE")
+			return err
+		},
+		"Query": func(db *DB) error {
+			rows, err := db.QueryContext(context.TODO(), "SELECT")
+			if err == nil {
+				assert.True(t, rows.Next())
+				rows.Close()
+			}
+			return err
+		},
+		"QueryRow": func(db *DB) error {
+			var val int
+			err := db.QueryRowContext(context.TODO(), "SELECT").Scan(&val)
+			if err == nil {
+				assert.Equal(t, 1, val)
+			}
+			return err
+		},
+	}
+
+	for _, test := range tests {
+		for name, op := range ops {
+			t.Run(test.Name+"/"+name, func(t *testing.T) {
+				drv := &fakeDriver{errs: append([]error{}, test.Errs...)}
+				db := New(
+					sql.OpenDB(drv),
+					retrier.NewRetrier(2, retrier.NoDelay()),
+					WithTransient(func(err error) bool {
+						return err.Error() == errTransient.Error()
+					}),
+				)
+
+				err := op(db)
+
+				if test.Error != nil {
+					assert.ErrorContains(t, err, test.Error.Error())
+				} else {
+					assert.NoError(t, err)
+				}
+				assert.Len(t, drv.calls, test.Calls)
+			})
+		}
+	}
+}
+
+// TestDBBadConn tests if operations that fail with broken connections are
+// retried by default
+func TestDBBadConn(t *testing.T) {
+	drv := &fakeDriver{errs: []error{
+		driver.ErrBadConn,
+		driver.ErrBadConn,
+		driver.ErrBadConn,
+		driver.ErrBadConn,
+	}}
+	db := New(sql.OpenDB(drv), retrier.NewRetrier(2, retrier.NoDelay()))
+
+	_, err := db.ExecContext(context.TODO(), "UPDATE")
+
+	assert.NoError(t, err)
+	assert.Len(t, drv.calls, 5)
+}
+
+// TestDBDrained tests if queries are not executed after the retrier was
+// drained, including queries of a single row
+func TestDBDrained(t *testing.T) {
+	drv := &fakeDriver{}
+	retr := retrier.NewRetrier(2, retrier.NoDelay())
+	db := New(sql.OpenDB(drv), retr)
+	retr.Drain()
+
+	_, err := db.QueryContext(context.TODO(), "SELECT")
+	assert.ErrorIs(t, err, retrier.ErrShuttingDown)
+
+	var value int
+	err = db.QueryRowContext(context.TODO(), "SELECT").Scan(&value)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, drv.calls)
+}
@@ -0,0 +1,55 @@
+package retriersql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Soreing/retrier"
+)
+
+// RunTx runs a function in a transaction, and retries the whole transaction
+// when it fails with a transient error. A failed transaction is rolled back
+// before it is retried, and the function runs in a new transaction on every
+// attempt, so it should not have side effects outside of the transaction. The
+// transaction is committed when the function returns without an error.
+//
+// Without a classifier, only driver.ErrBadConn is transient, so serialization
+// failures and deadlocks are not retried. Pass the classifier of the database
+// with WithTransient, such as PostgresRetryable or retriermysql.Retryable:
+//
+//	err := retriersql.RunTx(ctx, db, r, fn,
+//		retriersql.WithTransient(retriersql.PostgresRetryable),
+//	)
+func RunTx(
+	ctx context.Context,
+	db *sql.DB,
+	r *retrier.Retrier,
+	fn func(tx *sql.Tx) error,
+	opts ...Option,
+) error {
+	cfg := newConfig(opts)
+	return r.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		err := runTx(ctx, db, cfg.txOpts, fn)
+		return err, cfg.retryable(err)
+	})
+}
+
+// runTx runs a function in a transaction, and commits the transaction if the
+// function succeeds or rolls it back otherwise.
+func runTx(
+	ctx context.Context,
+	db *sql.DB,
+	opts *sql.TxOptions,
+	fn func(tx *sql.Tx) error,
+) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,126 @@
+package retriersql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRunTx tests if transactions that fail with transient errors are rolled
+// back and retried as a whole, and committed when they succeed
+func TestRunTx(t *testing.T) {
+	errSerialization := fmt.Errorf("serialization failure")
+	errFatal := fmt.Errorf("fatal")
+
+	tests := []struct {
+		Name  string
+		Errs  []error
+		Calls []string
+		Error error
+	}{
+		{
+			Name:  "Committed",
+			Errs:  []error{},
+			Calls: []string{"BEGIN", "UPDATE", "COMMIT"},
+			Error: nil,
+		},
+		{
+			Name: "Retried after statement failure",
+			Errs: []error{nil, errSerialization},
+			Calls: []string{
+				"BEGIN", "UPDATE", "ROLLBACK",
+				"BEGIN", "UPDATE", "COMMIT",
+			},
+			Error: nil,
+		},
+		{
+			Name: "Retried after commit failure",
+			Errs: []error{nil, nil, errSerialization},
+			Calls: []string{
+				"BEGIN", "UPDATE", "COMMIT",
+				"BEGIN", "UPDATE", "COMMIT",
+			},
+			Error: nil,
+		},
+		{
+			Name:  "Fatal error is returned",
+			Errs:  []error{nil, errFatal},
+			Calls: []string{"BEGIN", "UPDATE", "ROLLBACK"},
+			Error: errFatal,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			drv := &fakeDriver{errs: append([]error{}, test.Errs...)}
+
+			err := RunTx(
+				context.TODO(),
+				sql.OpenDB(drv),
+				retrier.NewRetrier(2, retrier.NoDelay()),
+				func(tx *sql.Tx) error {
+					_, err := tx.ExecContext(context.TODO(), "UPDATE")
+					return err
+				},
+				WithTransient(func(err error) bool {
+					return err == errSerialization
+				}),
+			)
+
+			assert.Equal(t, test.Error, err)
+			assert.Equal(t, test.Calls, drv.calls)
+		})
+	}
+}
+
+// TestRunTxPostgres tests if transactions that fail with a serialization
+// failure of PostgreSQL are retried with PostgresRetryable, and are not
+// retried without a classifier
+func TestRunTxPostgres(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Options []Option
+		Calls   []string
+		Error   error
+	}{
+		{
+			Name:    "Retried with PostgresRetryable",
+			Options: []Option{WithTransient(PostgresRetryable)},
+			Calls: []string{
+				"BEGIN", "UPDATE", "ROLLBACK",
+				"BEGIN", "UPDATE", "COMMIT",
+			},
+			Error: nil,
+		},
+		{
+			Name:    "Returned without a classifier",
+			Options: nil,
+			Calls:   []string{"BEGIN", "UPDATE", "ROLLBACK"},
+			Error:   &pgError{code: "40001"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			drv := &fakeDriver{errs: []error{nil, &pgError{code: "40001"}}}
+
+			err := RunTx(
+				context.TODO(),
+				sql.OpenDB(drv),
+				retrier.NewRetrier(2, retrier.NoDelay()),
+				func(tx *sql.Tx) error {
+					_, err := tx.ExecContext(context.TODO(), "UPDATE")
+					return err
+				},
+				test.Options...,
+			)
+
+			assert.Equal(t, test.Error, err)
+			assert.Equal(t, test.Calls, drv.calls)
+		})
+	}
+}
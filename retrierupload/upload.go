@@ -0,0 +1,207 @@
+// Package retrierupload retries large uploads to object storage that are
+// split into parts, such as S3 multipart uploads or GCS resumable uploads, by
+// retrying every part independently with a retrier.
+package retrierupload
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Soreing/retrier"
+)
+
+// Part is a part of an upload.
+type Part struct {
+	// Number is the number of the part, starting from 1.
+	Number int
+
+	// Offset is the position of the first byte of the part in the object.
+	Offset int64
+
+	// Size is the number of bytes in the part.
+	Size int64
+
+	// URL is the address that the part is uploaded to, such as a presigned
+	// URL, which can be replaced by the refresh hook between attempts.
+	URL string
+
+	// Attempt is the number of the attempt of uploading the part, starting
+	// from 1.
+	Attempt int
+}
+
+// Split splits an object of some size into parts of a part size. The last
+// part holds the remaining bytes, so it may be smaller.
+func Split(size int64, partSize int64) []Part {
+	if partSize <= 0 {
+		partSize = size
+	}
+
+	parts := []Part{}
+	for off := int64(0); off < size; off += partSize {
+		n := partSize
+		if off+n > size {
+			n = size - off
+		}
+		parts = append(parts, Part{
+			Number: len(parts) + 1,
+			Offset: off,
+			Size:   n,
+		})
+	}
+	return parts
+}
+
+// Option configures an upload.
+type Option func(*config)
+
+// config is the configuration of an upload.
+type config struct {
+	maxElapsed  time.Duration
+	concurrency int
+	refresh     func(ctx context.Context, part *Part) error
+}
+
+// WithMaxElapsedTime limits the total elapsed time of the upload across all
+// of its parts. When the limit is reached, attempts in flight are canceled and
+// parts stop retrying with a *retrier.ExhaustedError that matches
+// retrier.ErrMaxElapsedTime.
+func WithMaxElapsedTime(d time.Duration) Option {
+	return func(c *config) {
+		c.maxElapsed = d
+	}
+}
+
+// WithConcurrency sets the number of parts that are uploaded at the same
+// time. The default is 1, which uploads parts in order.
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithRefresh sets a hook that is called before every retry of a part, which
+// can update the part, for example to sign its URL again when the signature
+// may have expired. If the hook fails, the attempt fails with its error.
+func WithRefresh(refresh func(ctx context.Context, part *Part) error) Option {
+	return func(c *config) {
+		c.refresh = refresh
+	}
+}
+
+// Upload uploads the parts of an object with an upload function, and returns
+// the results of every part in order, such as the ETags needed to complete a
+// multipart upload. Every part is retried independently in its own run of the
+// retrier, so each part has its own retries, while the stats, the drain and
+// the budget of the retrier see every part. When a part fails, the upload is
+// canceled and the error of the part is returned. Once every part succeeded,
+// the upload succeeds even if its context expires right after.
+func Upload[T any](
+	ctx context.Context,
+	r *retrier.Retrier,
+	parts []Part,
+	upload func(ctx context.Context, part Part) (T, error),
+	opts ...Option,
+) ([]T, error) {
+	cfg := &config{concurrency: 1}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.concurrency < 1 {
+		cfg.concurrency = 1
+	}
+
+	var deadline time.Time
+	if cfg.maxElapsed > 0 {
+		deadline = time.Now().Add(cfg.maxElapsed)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]T, len(parts))
+	sem := make(chan struct{}, cfg.concurrency)
+	wg := sync.WaitGroup{}
+	once := sync.Once{}
+	var failed error
+	var done atomic.Int64
+
+start:
+	for i := range parts {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break start
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			res, err := uploadPart(ctx, r, cfg, deadline, parts[i], upload)
+			if err != nil {
+				once.Do(func() {
+					failed = err
+					cancel()
+				})
+				return
+			}
+			results[i] = res
+			done.Add(1)
+		}(i)
+	}
+	wg.Wait()
+
+	if failed != nil {
+		return nil, failed
+	} else if done.Load() < int64(len(parts)) {
+		return nil, ctx.Err()
+	}
+	return results, nil
+}
+
+// uploadPart uploads a part with the retrier, limited by the deadline of the
+// upload if it has one. The deadline cancels the context of the part, so the
+// part runs on the retrier itself, and a part that runs out of time fails as
+// exhausted by the elapsed time limit.
+func uploadPart[T any](
+	ctx context.Context,
+	r *retrier.Retrier,
+	cfg *config,
+	deadline time.Time,
+	part Part,
+	upload func(ctx context.Context, part Part) (T, error),
+) (T, error) {
+	st := time.Now()
+	var last error
+	res, err := retrier.Do(ctx, r, func(ctx context.Context) (T, error, bool) {
+		part.Attempt++
+		if part.Attempt > 1 && cfg.refresh != nil {
+			if err := cfg.refresh(ctx, &part); err != nil {
+				var zero T
+				last = err
+				return zero, err, true
+			}
+		}
+		res, err := upload(ctx, part)
+		last = err
+		return res, err, err != nil
+	})
+
+	if errors.Is(err, context.DeadlineExceeded) &&
+		!deadline.IsZero() && !time.Now().Before(deadline) {
+		return res, &retrier.ExhaustedError{
+			Reason:   retrier.ErrMaxElapsedTime,
+			Attempts: part.Attempt,
+			Elapsed:  time.Since(st),
+			LastErr:  last,
+		}
+	}
+	return res, err
+}
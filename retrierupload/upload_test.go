@@ -0,0 +1,206 @@
+package retrierupload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSplit tests if objects are split into parts of the part size with the
+// remaining bytes in the last part
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Size     int64
+		PartSize int64
+		Parts    []Part
+	}{
+		{
+			Name:     "Even parts",
+			Size:     20,
+			PartSize: 10,
+			Parts: []Part{
+				{Number: 1, Offset: 0, Size: 10},
+				{Number: 2, Offset: 10, Size: 10},
+			},
+		},
+		{
+			Name:     "Smaller last part",
+			Size:     25,
+			PartSize: 10,
+			Parts: []Part{
+				{Number: 1, Offset: 0, Size: 10},
+				{Number: 2, Offset: 10, Size: 10},
+				{Number: 3, Offset: 20, Size: 5},
+			},
+		},
+		{
+			Name:     "Single part",
+			Size:     5,
+			PartSize: 0,
+			Parts: []Part{
+				{Number: 1, Offset: 0, Size: 5},
+			},
+		},
+		{
+			Name:     "Empty object",
+			Size:     0,
+			PartSize: 10,
+			Parts:    []Part{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Parts, Split(test.Size, test.PartSize))
+		})
+	}
+}
+
+// TestUpload tests if every part is retried with its own retries in a run of
+// the retrier, and the upload fails with the error of a part that runs out of
+// retries
+func TestUpload(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Failures map[int]int
+		Opts     []Option
+		Results  []string
+		Runs     int64
+		Err      error
+	}{
+		{
+			Name:     "Success",
+			Failures: map[int]int{},
+			Results:  []string{"etag-1", "etag-2", "etag-3"},
+			Runs:     3,
+		},
+		{
+			Name:     "Parts retried independently",
+			Failures: map[int]int{1: 2, 2: 2, 3: 2},
+			Results:  []string{"etag-1", "etag-2", "etag-3"},
+			Runs:     3,
+		},
+		{
+			Name:     "Concurrent parts",
+			Failures: map[int]int{2: 1},
+			Opts:     []Option{WithConcurrency(3)},
+			Results:  []string{"etag-1", "etag-2", "etag-3"},
+			Runs:     3,
+		},
+		{
+			Name:     "Part runs out of retries",
+			Failures: map[int]int{2: 3},
+			Runs:     2,
+			Err:      retrier.ErrMaxRetriesExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mu := sync.Mutex{}
+			upload := func(ctx context.Context, part Part) (string, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if test.Failures[part.Number] > 0 {
+					test.Failures[part.Number]--
+					return "", errors.New("connection reset")
+				}
+				return fmt.Sprintf("etag-%d", part.Number), nil
+			}
+
+			retr := retrier.NewRetrier(2, retrier.NoDelay())
+			res, err := Upload(context.Background(), retr, Split(30, 10), upload, test.Opts...)
+
+			if test.Err != nil {
+				assert.ErrorIs(t, err, test.Err)
+				assert.Nil(t, res)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.Results, res)
+			}
+			assert.Equal(t, test.Runs, retr.Stats().Runs)
+		})
+	}
+}
+
+// TestUploadRefresh tests if the refresh hook updates parts before every
+// retry, but not before the first attempt
+func TestUploadRefresh(t *testing.T) {
+	parts := Split(10, 10)
+	parts[0].URL = "signed-1"
+
+	var urls []string
+	upload := func(ctx context.Context, part Part) (int, error) {
+		urls = append(urls, part.URL)
+		if part.Attempt < 3 {
+			return 0, errors.New("signature expired")
+		}
+		return part.Attempt, nil
+	}
+	refresh := func(ctx context.Context, part *Part) error {
+		part.URL = fmt.Sprintf("signed-%d", part.Attempt)
+		return nil
+	}
+
+	retr := retrier.NewRetrier(5, retrier.NoDelay())
+	res, err := Upload(context.Background(), retr, parts, upload, WithRefresh(refresh))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3}, res)
+	assert.Equal(t, []string{"signed-1", "signed-2", "signed-3"}, urls)
+}
+
+// TestUploadMaxElapsedTime tests if the upload stops when the total elapsed
+// time of all parts reaches the limit
+func TestUploadMaxElapsedTime(t *testing.T) {
+	ch := make(chan error)
+	var attempts int32
+	upload := func(ctx context.Context, part Part) (int, error) {
+		atomic.AddInt32(&attempts, 1)
+		return 0, errors.New("error")
+	}
+
+	go func() {
+		retr := retrier.NewForeverRetrier(retrier.ConstantDelay(time.Millisecond * 20))
+		_, err := Upload(
+			context.Background(), retr, Split(20, 10), upload,
+			WithMaxElapsedTime(time.Millisecond*50),
+		)
+		ch <- err
+	}()
+
+	select {
+	case <-time.After(time.Second):
+		panic("test function hang")
+	case err := <-ch:
+		assert.ErrorIs(t, err, retrier.ErrMaxElapsedTime)
+		assert.LessOrEqual(t, atomic.LoadInt32(&attempts), int32(3))
+	}
+}
+
+// TestUploadMaxElapsedTimeSuccess tests if the upload succeeds when every part
+// succeeded, even though the limit is reached right after
+func TestUploadMaxElapsedTimeSuccess(t *testing.T) {
+	upload := func(ctx context.Context, part Part) (string, error) {
+		time.Sleep(time.Millisecond * 40)
+		return fmt.Sprintf("etag-%d", part.Number), nil
+	}
+
+	retr := retrier.NewRetrier(2, retrier.NoDelay())
+	res, err := Upload(
+		context.Background(), retr, Split(10, 10), upload,
+		WithMaxElapsedTime(time.Millisecond*20),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"etag-1"}, res)
+	assert.Equal(t, int64(1), retr.Stats().Successes)
+}
@@ -0,0 +1,205 @@
+// Package retrierws keeps websocket connections open with a retrier, dialing
+// them again when they are closed or fail.
+package retrierws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Soreing/retrier"
+)
+
+// ErrNotConnected is returned when a message is written while the connection
+// is being dialed again.
+var ErrNotConnected = errors.New("websocket is not connected")
+
+// DefaultStablePeriod is the default time that a connection must stay open
+// before the backoff of reconnecting is reset.
+const DefaultStablePeriod = time.Minute
+
+// Conn is the interface of websocket connections, which is implemented by
+// *websocket.Conn of gorilla/websocket.
+type Conn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	Close() error
+}
+
+// Dialer dials a websocket connection.
+type Dialer func(ctx context.Context) (Conn, error)
+
+// stableError is the error of a connection that was closed after it was open
+// for the stable period, which ends the run of the retrier as a success so the
+// backoff is reset.
+type stableError struct {
+	err error
+}
+
+// Error returns the error that closed the connection.
+func (e *stableError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error that closed the connection.
+func (e *stableError) Unwrap() error {
+	return e.err
+}
+
+// ReconnectingConn is a websocket connection that is dialed again with a
+// retrier when it is closed or fails. Connections that stay open for the
+// stable period reset the backoff of the retrier, while connections that
+// fail sooner count as failed attempts, so a server that accepts connections
+// and drops them right away is not dialed in a tight loop. Messages can be
+// written while the connection is read.
+type ReconnectingConn struct {
+	retr      *retrier.Retrier
+	dial      Dialer
+	stable    time.Duration
+	onConnect func(ctx context.Context, conn Conn) error
+
+	mu   sync.Mutex
+	conn Conn
+}
+
+// Option configures a reconnecting connection.
+type Option func(*ReconnectingConn)
+
+// WithStablePeriod sets the time that a connection must stay open before the
+// backoff of reconnecting is reset, replacing DefaultStablePeriod.
+func WithStablePeriod(d time.Duration) Option {
+	return func(c *ReconnectingConn) {
+		c.stable = d
+	}
+}
+
+// WithOnConnect sets a hook that is called after every connection is dialed,
+// before its messages are read, for example to subscribe to channels again.
+// If the hook fails, the connection is closed and the attempt fails.
+func WithOnConnect(onConnect func(ctx context.Context, conn Conn) error) Option {
+	return func(c *ReconnectingConn) {
+		c.onConnect = onConnect
+	}
+}
+
+// NewReconnectingConn creates a connection that is dialed with a dialer, and
+// dialed again with a retrier. The connection is not dialed until Run is
+// called.
+func NewReconnectingConn(
+	r *retrier.Retrier,
+	dial Dialer,
+	opts ...Option,
+) *ReconnectingConn {
+	c := &ReconnectingConn{
+		retr:   r,
+		dial:   dial,
+		stable: DefaultStablePeriod,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run dials the connection and reads its messages with a handler, dialing it
+// again whenever it fails. Run stops when the context is done, or when the
+// retrier gives up dialing the connection. Connections that stayed open for
+// the stable period end the run of the retrier as a success. Connections are
+// dialed with the context of the attempt, so attempt timeouts of the retrier
+// apply to dialing and the connect hook, but connections stay open for longer
+// than attempts, so they are detached from the attempt once they are open.
+func (c *ReconnectingConn) Run(
+	ctx context.Context,
+	handle func(messageType int, data []byte),
+) error {
+	for {
+		stable := false
+		err := c.retr.RunCtx(ctx, func(actx context.Context) (error, bool) {
+			err, retry := c.serve(ctx, actx, handle)
+
+			var serr *stableError
+			if errors.As(err, &serr) {
+				stable = true
+				return nil, false
+			}
+			return err, retry
+		})
+		if !stable {
+			return err
+		}
+	}
+}
+
+// WriteMessage writes a message to the current connection. If the connection
+// is being dialed again, ErrNotConnected is returned.
+func (c *ReconnectingConn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// serve dials a connection in an attempt and reads its messages until it
+// fails.
+func (c *ReconnectingConn) serve(
+	ctx context.Context,
+	actx context.Context,
+	handle func(messageType int, data []byte),
+) (error, bool) {
+	sctx, detach, cancel := retrier.DetachAttempt(ctx, actx)
+	defer cancel()
+
+	conn, err := c.dial(sctx)
+	if err != nil {
+		return err, ctx.Err() == nil
+	}
+	if c.onConnect != nil {
+		if err := c.onConnect(sctx, conn); err != nil {
+			conn.Close()
+			return err, ctx.Err() == nil
+		}
+	}
+	detach()
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+
+	// Reading blocks until the connection fails, so the connection is closed
+	// when the context is done to stop reading.
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	st := time.Now()
+	for {
+		var mt int
+		var data []byte
+		if mt, data, err = conn.ReadMessage(); err != nil {
+			break
+		}
+		handle(mt, data)
+	}
+	close(done)
+
+	c.mu.Lock()
+	c.conn = nil
+	c.mu.Unlock()
+	conn.Close()
+
+	if ctx.Err() != nil {
+		return ctx.Err(), false
+	} else if time.Since(st) >= c.stable {
+		return &stableError{err}, false
+	}
+	return err, true
+}
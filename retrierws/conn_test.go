@@ -0,0 +1,235 @@
+package retrierws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Soreing/retrier"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn is a connection that returns messages from a list, and then fails
+// when its list runs out or it is closed.
+type fakeConn struct {
+	mu       sync.Mutex
+	msgs     []string
+	written  []string
+	closed   chan struct{}
+	once     sync.Once
+	blocking bool
+}
+
+func newFakeConn(blocking bool, msgs ...string) *fakeConn {
+	return &fakeConn{
+		msgs:     msgs,
+		closed:   make(chan struct{}),
+		blocking: blocking,
+	}
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) {
+	c.mu.Lock()
+	if len(c.msgs) > 0 {
+		msg := c.msgs[0]
+		c.msgs = c.msgs[1:]
+		c.mu.Unlock()
+		return 1, []byte(msg), nil
+	}
+	c.mu.Unlock()
+
+	if c.blocking {
+		<-c.closed
+	}
+	return 0, nil, io.ErrUnexpectedEOF
+}
+
+func (c *fakeConn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = append(c.written, string(data))
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// TestRun tests if connections are dialed again when they fail, with the
+// backoff reset only after connections that stayed open for the stable period,
+// which end the run of the retrier as a success
+func TestRun(t *testing.T) {
+	errDial := errors.New("connection refused")
+
+	tests := []struct {
+		Name     string
+		Stable   time.Duration
+		Conns    []*fakeConn
+		DialErrs []error
+		Msgs     []string
+		Dials    int
+		Runs     int64
+		Success  int64
+		Err      string
+	}{
+		{
+			Name:   "Stable connections reset backoff",
+			Stable: 0,
+			Conns: []*fakeConn{
+				newFakeConn(false, "a"),
+				newFakeConn(false, "b"),
+				newFakeConn(false, "c"),
+			},
+			DialErrs: []error{nil, errDial, nil, errDial, nil, errDial, errDial},
+			Msgs:     []string{"a", "b", "c"},
+			Dials:    7,
+			Runs:     4,
+			Success:  3,
+			Err:      "failed after max retries: connection refused",
+		},
+		{
+			Name:   "Unstable connections count as failed attempts",
+			Stable: time.Hour,
+			Conns: []*fakeConn{
+				newFakeConn(false, "a"),
+				newFakeConn(false, "b"),
+			},
+			DialErrs: []error{nil, nil},
+			Msgs:     []string{"a", "b"},
+			Dials:    2,
+			Runs:     1,
+			Success:  0,
+			Err:      "failed after max retries: unexpected EOF",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			dials := 0
+			conns := test.Conns
+			dial := func(ctx context.Context) (Conn, error) {
+				err := test.DialErrs[dials]
+				dials++
+				if err != nil {
+					return nil, err
+				}
+				conn := conns[0]
+				conns = conns[1:]
+				return conn, nil
+			}
+
+			var msgs []string
+			retr := retrier.NewRetrier(1, retrier.NoDelay())
+			conn := NewReconnectingConn(
+				retr,
+				dial,
+				WithStablePeriod(test.Stable),
+			)
+			err := conn.Run(context.Background(), func(mt int, data []byte) {
+				msgs = append(msgs, string(data))
+			})
+
+			assert.EqualError(t, err, test.Err)
+			assert.Equal(t, test.Msgs, msgs)
+			assert.Equal(t, test.Dials, dials)
+			assert.Equal(t, test.Runs, retr.Stats().Runs)
+			assert.Equal(t, test.Success, retr.Stats().Successes)
+		})
+	}
+}
+
+// TestRunOnConnect tests if the connect hook is called for every connection,
+// and messages can be written while the connection is open
+func TestRunOnConnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := newFakeConn(false)
+	second := newFakeConn(true)
+	conns := []*fakeConn{first, second}
+	dial := func(ctx context.Context) (Conn, error) {
+		conn := conns[0]
+		conns = conns[1:]
+		return conn, nil
+	}
+
+	conn := NewReconnectingConn(
+		retrier.NewRetrier(3, retrier.NoDelay()),
+		dial,
+		WithOnConnect(func(ctx context.Context, conn Conn) error {
+			return conn.WriteMessage(1, []byte("subscribe"))
+		}),
+	)
+
+	ch := make(chan error)
+	go func() {
+		ch <- conn.Run(ctx, func(int, []byte) {})
+	}()
+
+	assert.Eventually(t, func() bool {
+		return conn.WriteMessage(1, []byte("ping")) == nil
+	}, time.Second, time.Millisecond)
+	cancel()
+
+	select {
+	case <-time.After(time.Second):
+		panic("test function hang")
+	case err := <-ch:
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+
+	assert.Equal(t, []string{"subscribe"}, first.written)
+	assert.Equal(t, []string{"subscribe", "ping"}, second.written)
+	assert.ErrorIs(t, conn.WriteMessage(1, []byte("ping")), ErrNotConnected)
+}
+
+// TestRunAttemptTimeout tests if connections are dialed with the context of
+// the attempt, and if they stay open after the attempt timeout
+func TestRunAttemptTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	open := newFakeConn(true)
+	dials := 0
+	dial := func(ctx context.Context) (Conn, error) {
+		dials++
+		if dials == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return open, nil
+	}
+
+	conn := NewReconnectingConn(
+		retrier.NewRetrier(
+			3,
+			retrier.NoDelay(),
+			retrier.WithAttemptTimeout(20*time.Millisecond),
+		),
+		dial,
+	)
+
+	ch := make(chan error)
+	go func() {
+		ch <- conn.Run(ctx, func(int, []byte) {})
+	}()
+
+	assert.Eventually(t, func() bool {
+		return conn.WriteMessage(1, []byte("ping")) == nil
+	}, time.Second, time.Millisecond)
+	time.Sleep(40 * time.Millisecond)
+	assert.NoError(t, conn.WriteMessage(1, []byte("ping")))
+	cancel()
+
+	select {
+	case <-time.After(time.Second):
+		panic("test function hang")
+	case err := <-ch:
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+	assert.Equal(t, 2, dials)
+}
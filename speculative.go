@@ -0,0 +1,137 @@
+package retrier
+
+import (
+	"context"
+	"time"
+)
+
+// Speculation is the pending verification of a value that a speculative run
+// returned before it was confirmed.
+type Speculation struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+// settle completes the speculation with its final value and error.
+func (s *Speculation) settle(value any, err error) {
+	s.value, s.err = value, err
+	close(s.done)
+}
+
+// Done returns a channel that is closed when the speculation has settled.
+func (s *Speculation) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait blocks until the speculation has settled, and returns the final value
+// and error. If the verification succeeded, the value is the one that was
+// returned early. If it failed, the value is the result of the run that was
+// retried in its place.
+func (s *Speculation) Wait() (any, error) {
+	<-s.done
+	return s.value, s.err
+}
+
+// RunSpeculativeCtx executes a work task in the context of a retrier the same
+// way as RunCtx, except that the task also returns a value and an optional
+// verification function. When an attempt succeeds, its value is returned
+// immediately without waiting for confirmation, and the verification runs in
+// the background with the window as its timeout.
+//
+// If the verification fails within the window, the task is retried in the
+// background with the policy of the retrier, waiting for the verification of
+// each successful attempt before accepting it. If the verification does not
+// fail within the window, the value is considered confirmed. The returned
+// speculation settles with the final value once this has been decided.
+//
+// The background work uses the same context as the run, so canceling it
+// aborts both the verification and the retries, and the speculation settles
+// with the error of the context. If the run fails, the error is returned and
+// the speculation is already settled with the same error.
+func (r *Retrier) RunSpeculativeCtx(
+	ctx context.Context,
+	window time.Duration,
+	work func(ctx context.Context) (any, error, bool, func(ctx context.Context) error),
+) (any, *Speculation, error) {
+	spec := &Speculation{done: make(chan struct{})}
+
+	var value any
+	var verify func(context.Context) error
+	err := r.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		var err error
+		var ret bool
+		value, err, ret, verify = work(ctx)
+		return err, ret
+	})
+	if err != nil {
+		spec.settle(nil, err)
+		return nil, spec, err
+	}
+
+	go func() {
+		err := runVerify(ctx, window, verify)
+		if err == nil {
+			spec.settle(value, nil)
+		} else if ctx.Err() != nil {
+			spec.settle(value, ctx.Err())
+		} else {
+			spec.settle(r.runVerified(ctx, window, work))
+		}
+	}()
+
+	return value, spec, nil
+}
+
+// runVerified executes a work task in the context of a retrier, and waits for
+// the verification of each successful attempt before accepting its value.
+// Attempts that fail verification are retried.
+func (r *Retrier) runVerified(
+	ctx context.Context,
+	window time.Duration,
+	work func(ctx context.Context) (any, error, bool, func(ctx context.Context) error),
+) (any, error) {
+	var value any
+	err := r.RunCtx(ctx, func(ctx context.Context) (error, bool) {
+		var err error
+		var ret bool
+		var verify func(context.Context) error
+		value, err, ret, verify = work(ctx)
+		if err != nil {
+			return err, ret
+		} else if err := runVerify(ctx, window, verify); err != nil {
+			return err, ctx.Err() == nil
+		}
+		return nil, false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// runVerify runs a verification function with a window as its timeout. The
+// verification only fails if it returns an error within the window, or if the
+// parent context has been canceled.
+func runVerify(
+	ctx context.Context,
+	window time.Duration,
+	verify func(ctx context.Context) error,
+) error {
+	if verify == nil {
+		return nil
+	}
+
+	vctx, cncl := context.WithTimeout(ctx, window)
+	defer cncl()
+
+	err := verify(vctx)
+	if err == nil {
+		return nil
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	} else if vctx.Err() != nil {
+		return nil
+	}
+	return err
+}
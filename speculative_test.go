@@ -0,0 +1,84 @@
+package retrier
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRunSpeculativeCtx tests if a speculative run returns the value of the
+// first successful attempt immediately, and retries the task in the background
+// if the verification of the value fails
+func TestRunSpeculativeCtx(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Verify   []error
+		Value    any
+		Final    any
+		Attempts int
+	}{
+		{
+			Name:     "Verification passes",
+			Verify:   []error{nil},
+			Value:    1,
+			Final:    1,
+			Attempts: 1,
+		},
+		{
+			Name:     "Verification fails and triggers a retry",
+			Verify:   []error{fmt.Errorf("stale value"), nil},
+			Value:    1,
+			Final:    2,
+			Attempts: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			retr := NewRetrier(5, ConstantDelay(time.Millisecond))
+
+			cnt := 0
+			work := func(ctx context.Context) (
+				any, error, bool, func(context.Context) error,
+			) {
+				cnt++
+				idx := cnt - 1
+				return cnt, nil, false, func(ctx context.Context) error {
+					time.Sleep(time.Millisecond * 10)
+					return test.Verify[idx]
+				}
+			}
+
+			st := time.Now()
+			value, spec, err := retr.RunSpeculativeCtx(
+				context.TODO(),
+				time.Millisecond*100,
+				work,
+			)
+			dif := time.Since(st)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.Value, value)
+			assert.Less(t, dif, time.Millisecond*10)
+
+			var final any
+			ch := make(chan bool)
+			go func() {
+				final, err = spec.Wait()
+				ch <- true
+			}()
+			select {
+			case <-time.After(time.Second):
+				panic("test function hang")
+			case <-ch:
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.Final, final)
+			assert.Equal(t, test.Attempts, cnt)
+		})
+	}
+}
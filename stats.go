@@ -0,0 +1,116 @@
+package retrier
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the counters of the runs of a retrier.
+type Stats struct {
+	// Runs is the number of runs, excluding the runs that were rejected
+	// because the retrier is shutting down.
+	Runs int64
+
+	// Attempts is the number of attempts of tasks.
+	Attempts int64
+
+	// Retries is the number of retries of tasks.
+	Retries int64
+
+	// Successes is the number of runs where the task succeeded.
+	Successes int64
+
+	// Exhaustions is the number of runs that the retrier gave up on with an
+	// *ExhaustedError.
+	Exhaustions int64
+
+	// Sleep is the total delay before retries.
+	Sleep time.Duration
+
+	// AttemptsToSuccess is the distribution of the number of attempts that
+	// runs needed before the task succeeded.
+	AttemptsToSuccess AttemptHistogram
+}
+
+// AttemptHistogram is the distribution of the number of attempts that runs
+// needed before the task succeeded, which is the key signal for tuning the
+// limit of retries. Runs that the retrier gave up on with an *ExhaustedError
+// are counted separately.
+type AttemptHistogram struct {
+	One         int64
+	Two         int64
+	ThreeToFive int64
+	SixToTen    int64
+	OverTen     int64
+	Exhausted   int64
+}
+
+// statsState holds the counters of the runs of a retrier.
+type statsState struct {
+	runs        atomic.Int64
+	attempts    atomic.Int64
+	retries     atomic.Int64
+	successes   atomic.Int64
+	exhaustions atomic.Int64
+	sleep       atomic.Int64
+
+	// buckets count the successful runs by the number of attempts, which are
+	// 1, 2, 3-5, 6-10 and more than 10.
+	buckets [5]atomic.Int64
+}
+
+// Stats returns a snapshot of the counters of the runs of the retrier, such as
+// to compare the behavior of a policy before and after tuning it. Counters of
+// runs in flight may be updated while the snapshot is taken. Derived retriers
+// keep their own counters, which start from zero.
+func (r *Retrier) Stats() Stats {
+	return Stats{
+		Runs:        r.stats.runs.Load(),
+		Attempts:    r.stats.attempts.Load(),
+		Retries:     r.stats.retries.Load(),
+		Successes:   r.stats.successes.Load(),
+		Exhaustions: r.stats.exhaustions.Load(),
+		Sleep:       time.Duration(r.stats.sleep.Load()),
+		AttemptsToSuccess: AttemptHistogram{
+			One:         r.stats.buckets[0].Load(),
+			Two:         r.stats.buckets[1].Load(),
+			ThreeToFive: r.stats.buckets[2].Load(),
+			SixToTen:    r.stats.buckets[3].Load(),
+			OverTen:     r.stats.buckets[4].Load(),
+			Exhausted:   r.stats.exhaustions.Load(),
+		},
+	}
+}
+
+// countRun counts the outcome of a run.
+func (s *statsState) countRun(res Result, err error) {
+	s.runs.Add(1)
+	if err == nil {
+		s.successes.Add(1)
+		s.buckets[bucket(res.Attempts)].Add(1)
+	} else if _, ok := err.(*ExhaustedError); ok {
+		s.exhaustions.Add(1)
+	}
+}
+
+// countRetry counts a retry with the delay before it.
+func (s *statsState) countRetry(delay time.Duration) {
+	s.retries.Add(1)
+	s.sleep.Add(int64(delay))
+}
+
+// bucket returns the index of the bucket of a number of attempts.
+func bucket(attempts int) int {
+	switch {
+	case attempts <= 1:
+		return 0
+	case attempts == 2:
+		return 1
+	case attempts <= 5:
+		return 2
+	case attempts <= 10:
+		return 3
+	default:
+		return 4
+	}
+}
@@ -0,0 +1,81 @@
+package retrier
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStats tests if the counters of runs, attempts, retries, successes,
+// exhaustions and the total delay are updated by runs, and if derived
+// retriers keep their own counters
+func TestStats(t *testing.T) {
+	retr := NewRetrier(2, ConstantDelay(time.Millisecond))
+	assert.Equal(t, Stats{}, retr.Stats())
+
+	retr.Run(func() (error, bool) {
+		return fmt.Errorf("error"), true
+	})
+	count := 0
+	retr.Run(func() (error, bool) {
+		count++
+		return nil, count < 2
+	})
+	retr.Run(func() (error, bool) {
+		return fmt.Errorf("error"), false
+	})
+
+	assert.Equal(t, Stats{
+		Runs:        3,
+		Attempts:    6,
+		Retries:     3,
+		Successes:   1,
+		Exhaustions: 1,
+		Sleep:       time.Millisecond * 3,
+		AttemptsToSuccess: AttemptHistogram{
+			Two:       1,
+			Exhausted: 1,
+		},
+	}, retr.Stats())
+
+	derived := retr.WithMax(0)
+	derived.Run(func() (error, bool) {
+		return nil, false
+	})
+	assert.Equal(t, Stats{
+		Runs:              1,
+		Attempts:          1,
+		Successes:         1,
+		AttemptsToSuccess: AttemptHistogram{One: 1},
+	}, derived.Stats())
+	assert.Equal(t, int64(3), retr.Stats().Runs)
+
+	retr.Drain()
+	retr.Run(func() (error, bool) {
+		return nil, false
+	})
+	assert.Equal(t, int64(3), retr.Stats().Runs)
+}
+
+// TestAttemptsToSuccess tests if successful runs are counted in the buckets of
+// the number of attempts they needed
+func TestAttemptsToSuccess(t *testing.T) {
+	retr := NewRetrier(NoLimit, NoDelay())
+	for _, attempts := range []int{1, 1, 2, 3, 5, 6, 10, 11, 20} {
+		count := 0
+		retr.Run(func() (error, bool) {
+			count++
+			return nil, count < attempts
+		})
+	}
+
+	assert.Equal(t, AttemptHistogram{
+		One:         2,
+		Two:         1,
+		ThreeToFive: 2,
+		SixToTen:    2,
+		OverTen:     2,
+	}, retr.Stats().AttemptsToSuccess)
+}